@@ -0,0 +1,42 @@
+package gopatch
+
+import(
+  "context"
+  "reflect"
+)
+
+// Authorizer decides whether a field may be patched, using the context passed to Patcher.PatchContext. This allows permission decisions to be
+// made from the request's user, tenant or feature flags, rather than from static lists in the PatcherConfig.
+type Authorizer interface {
+
+  // Allow is called for each struct field in the patch, including embedded structs, once the field has passed every other permission check and
+  // before it's patched. "path" is the field's path in the patch in dot notation, "current" is the field's value, and "new" is its value in the
+  // patch. Fields which aren't allowed are reported in the PatchResult's Unpermitted array, while an error fails the patch.
+  Allow(ctx context.Context, path string, field reflect.StructField, current, new interface{}) (bool, error)
+}
+
+// AuthorizerFunc is an adapter allowing an ordinary function to be used as an Authorizer.
+type AuthorizerFunc func(ctx context.Context, path string, field reflect.StructField, current, new interface{}) (bool, error)
+
+// Allow calls f(ctx, path, field, current, new).
+func (f AuthorizerFunc) Allow(ctx context.Context, path string, field reflect.StructField, current, new interface{}) (bool, error) {
+
+  return f(ctx, path, field, current, new)
+}
+
+// rolesKey is the context key for the roles added by WithRoles.
+type rolesKey struct{}
+
+// WithRoles returns a copy of "ctx" holding the caller's "roles", which are checked against the "gopatch" tag option "roles" when the context is
+// passed to Patcher.PatchContext. See Patcher.PatchAs.
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+
+  return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// rolesFromContext returns the roles added to "ctx" by WithRoles.
+func rolesFromContext(ctx context.Context) []string {
+
+  roles, _ := ctx.Value(rolesKey{}).([]string)
+  return roles
+}
\ No newline at end of file
@@ -19,7 +19,7 @@
 //
 // Or configure your own!
 //
-//     patcher := gopatch.NewPatcher(gopatch.PatcherConfig{
+//     patcher, err := gopatch.NewPatcher(gopatch.PatcherConfig{
 //       PermittedFields: []string{ "username", "email_address" },
 //       UnpermittedErrors: true,
 //       PatchSource: "json",
@@ -35,12 +35,42 @@
 //     
 //     // err != nil
 //
+// Permitted Fields
+//
+// Each entry of `PermittedFields` is a pattern matched against field paths in dot notation, such as "profile.motto", and permits only the fields it
+// matches, so "profile" doesn't permit "profile.motto". Patterns ending in `*` or `**`, such as "profile.*", also permit everything inside them. A
+// `*` segment matches any single field name, map key or slice index, as in "addresses.*.city", `**` matches any number of them, and other segments
+// may be globs such as "addr*". Patterns starting with "!" carve exclusions out of broader grants, such as "!profile.internal_notes". Patterns are
+// compiled once by `New`, so long lists cost nothing extra per patch. `NewPatcher` returns an error if any of them are invalid, while a Patcher
+// created by `New` returns it from every patch.
+//
+// An empty `PermittedFields` permits every field, unless the Patcher is configured with `DenyByDefault`, in which case it permits none. Fields
+// can then only be patched if granted by a pattern or tagged with the gopatch tag "public", so emptying the list by mistake can't expose them.
+// "public" permits the tagged field alone, so the fields inside a public struct must be public or granted in turn, while structs containing public
+// fields are patched far enough to reach them.
+//
+// Fields can also be limited to callers with certain roles using the gopatch tag option "roles", such as `gopatch:"roles=admin|support"`. Pass the
+// caller's roles to `Patcher.PatchAs`, and the field is only permitted if they include one of those listed, in addition to any other restrictions.
+// The field is reported in Unpermitted otherwise. This keeps a single Patcher and the model definitions in charge of who may write what.
+//
+// Enum-like fields, such as statuses, can be limited to certain changes using the gopatch tag option "transitions", which lists the allowed changes
+// from the field's current value to the patched value, such as `gopatch:"transitions=draft>review,review>published,review>draft"`. Either side of
+// a transition may be "*" to match any value. Any other change is unpermitted, and reported or returned as an error like any unpermitted field.
+// The patched value is converted to the field's type first, and both values are written as their String method returns, if the type has one, so
+// an enum such as `type State int` is listed by its names.
+//
+// For decisions which depend on the request, such as its user, tenant or feature flags, configure an `Authorizer` and patch with
+// `Patcher.PatchContext`. The context is passed to the Authorizer for each field, and roles can be added to it with `gopatch.WithRoles`. Cancelling
+// the context stops the patch, undoing any changes already made.
+//
 // Some Limitations
 //
-// Currently, gopatch cannot patch maps, and cannot replace maps not of the same key AND value types. Additionally, gopatch cannot patch or replace
-// slices/arrays. Maps not of the same key and value types as well as all slices are currently skipped without error. However, it's easy to hook your
-// own patch/replace logic by adding a custom Updater function to `gopatch.Updaters`. Note that these functions are run first to last, so you'll need
-// to inject your function like so: `gopatch.Updaters = append(myUpdater, gopatch.Updaters...)`. Suggestions on how to remove these limitations are
+// Currently, gopatch cannot replace maps not of the same key AND value types, which are skipped, unless they are merged key by key
+// using the gopatch tag "merge" (see below). Slices and arrays are replaced element by element, with each element converted using the same rules as
+// any other field, so JSON-decoded `[]interface{}` values can patch fields such as `[]string`, `[3]int` or `[]MyStruct`. Slices of structs are built
+// from zero-value elements, deep-patched from each `map[string]interface{}` element. However, it's easy to hook your own patch/replace logic by
+// adding a custom Updater function to `gopatch.Updaters`. Note that these functions are run first to last, so you'll need to inject your function
+// like so: `gopatch.Updaters = append(myUpdater, gopatch.Updaters...)`. Suggestions on how to remove these limitations are
 // welcome. Please add an issue or make a pull request!
 //
 // Field Name Sources
@@ -59,6 +89,66 @@
 // of patching a nefarious user's account. If `UnpermittedErrors` were false, the patch would succeed and result would not be nil; however, the
 // Unpermitted array would contain "IsBanned", because the patching of that field wasn't permitted. Meanwhile, the "Fields" array would contain
 // "Username" because it was permitted, and Map would contain the same data as `nefariousPatchRequest`, but without "is_banned".
+// Unpermitted fields inside embedded structs, slices and maps are listed too, by their absolute path in dot notation prepended with `EmbedPath`,
+// such as "profile.is_verified". The UnpermittedReasons map holds why each was blocked, as one of the `UnpermittedReason` constants: by the gopatch
+// tag "-", `PermittedFields`, the "once" or "immutable" flags, the "roles" or "transitions" options, or the `Authorizer`.
+//
+// Fields whose patch value can't be patched into them, such as a string patched into a bool field, are skipped and listed in the Skipped array
+// along with the reason, so clients can be told their input was ignored. Patchers configured with `StrictTypes` return an error instead. Skipped
+// fields, and keys listed in the Unknown array, use the same absolute paths prepended with `EmbedPath` as Unpermitted fields.
+//
+// Results also contain a list of Changes, one for each patched field, holding the field's path in the same dot-notation as Map, its value before the
+// patch, and its value after the patch. These are useful for audit logs and change notifications, such as "email changed from A to B".
+//
+// Patch Errors
+//
+// Errors caused by a field, such as an unpermitted field or a value which can't be converted to the field's type, are returned as a `*PatchError`
+// holding the field's full path in the patch, the struct field's name, a machine-readable code, and the underlying cause. Use `errors.As` to
+// retrieve one, or `errors.Is` with a sentinel error such as `gopatch.ErrUnpermitted` to check its code. This allows API layers to map errors onto
+// per-field responses without matching on message text. Patchers configured with `CollectErrors` go through the whole patch instead of stopping
+// at the first failed field, returning an `ErrorList` of every PatchError found.
+//
+// Validation
+//
+// Fields can declare constraints in a "patchvalidate" tag, which are checked against each value after it's converted to the field's type, including
+// any conversion made by Updaters, and before it's assigned. The rules "min", "max" and "len" compare numbers by value, and strings, slices and maps
+// by length. "oneof" takes a space-separated list of allowed values, "regex" a regular expression strings must match, and "email" takes no value.
+//
+//     type User struct {
+//     
+//       Username string `json:"username" patchvalidate:"min=3,max=20,regex=^[a-z0-9_]+$"`
+//       Email    string `json:"email"    patchvalidate:"email"`
+//       Plan     string `json:"plan"     patchvalidate:"oneof=free pro"`
+//     }
+//
+// A value which fails a rule results in a PatchError with the code `gopatch.CodeValidation`, caused by a `*ValidationError` naming the rule.
+//
+// Invariants across fields, such as a start date preceding an end date, can be checked by implementing `gopatch.PatchValidator` on the struct. Its
+// ValidatePatch method is called once the struct's fields are patched, whether it's the destination or a struct patched inside it, and an error
+// fails the patch, undoing every change made by it.
+//
+// Setters
+//
+// Fields are assigned directly, unless the struct has a setter method for them, such as `SetEmail(email string) error` for a field named "Email"
+// or "email". A different method can be named with the gopatch tag option "setter", such as `gopatch:"setter=ChangeEmail"`. The patch value is
+// converted to the field's type, including by Updaters, and passed to the setter, which can enforce the type's invariants. An error from the
+// setter fails the patch. Setters also allow unexported fields to be patched, which are otherwise skipped. As a setter replaces its field as a
+// whole, the field itself must be permitted, and structs passed to it are built with every field the patch holds.
+//
+// Lifecycle Hooks
+//
+// Logic which belongs to a model, rather than to each handler patching it, can be kept on the model by implementing `gopatch.BeforePatcher` or
+// `gopatch.AfterPatcher`. These are detected on the destination and on any struct patched inside it. BeforePatch receives a copy of the struct's
+// patch, which it can rewrite or reject, before its fields are patched. AfterPatch receives the struct's results once its fields are patched, and
+// can update derived fields such as `UpdatedAt` or `Slug`, adding them to the results. An error from either hook fails the patch.
+//
+// Previewing Patches
+//
+// Patches are all-or-nothing. If a patch operation returns an error, every change it had already made to the structure is undone first.
+// `Patcher.Preview` runs a patch like `Patcher.Patch` and returns the same results or error, but patches a deep copy of the structure, leaving the
+// original untouched, so it's safe to preview while others read it. Lifecycle hooks and PatchValidators are called on the copy, while setters
+// aren't called at all, and the fields they would set are set directly on the copy. This can be used to show which fields a patch would change
+// before the user confirms it.
 //
 // Gopatch Field Tag
 //
@@ -68,15 +158,22 @@
 //
 //     type User struct {
 //     
-//       ID           int     `json:"id"        gopatch:"-"`        // NEVER patch this field, even if permitted in configuration.
+//       ID           int     `json:"id"        gopatch:"-"`          // NEVER patch this field, even if permitted in configuration.
+//     
+//       Username string      `json:"username"`                       // No gopatch tag allows normal patching behavior, acts like "patch" for structs.
+//     
+//       Profile  UserProfile `json:"profile"   gopatch:"patch"`      // Patch data for Profile will patch the fields inside.
 //     
-//       Username string      `json:"username"`                     // No gopatch tag allows normal patching behavior, acts like "patch" for structs.
+//       BanData  UserBanData `json:"ban_data"  gopatch:"replace"`    // Patch data for BanData will create a new zero-value BanData and patch that.
 //     
-//       Profile  UserProfile `json:"profile"   gopatch:"patch"`    // Patch data for Profile will patch the fields inside.
+//       Referrer string      `json:"referrer"  gopatch:"once"`       // Referrer can only be patched while it holds its zero value.
 //     
-//       BanData  UserBanData `json:"ban_data"  gopatch:"replace"`  // Patch data for BanData will create a new zero-value BanData and patch that.
+//       Region   string      `json:"region"    gopatch:"immutable"`  // Region can't be changed, though patches repeating its value are accepted.
 //     }
 //
+// Patches of immutable fields are converted to the field's type before being compared with its value, so a time patched as a string, or a struct
+// patched from a map holding every one of its fields, is accepted if it's equal to the field's value. Times are compared by instant.
+//
 // When the gopatch tag "patch" is used, the PatchResult's Map field will contain the struct field's values flattened with dot-notation keys created
 // using the absolute path to the struct patched. For example, if the above User struct's `Profile.Motto` field is patched, the result's Map field
 // would contain the following data: `"profile.motto": "..."`. This facilitates the patch-embedded-fields behavior of embedded objects in database
@@ -86,4 +183,26 @@
 // exactly as presented to the Patcher. For example, if the above User struct's `BanData.Length` field is patched, the result's Map field would
 // contain the following data: `"ban_data": map[string]interface{}{ "length": 30 }`. This facilitates the patch-whole-object behavior of embedded
 // objects in database servers such as MongoDB.
+//
+// Slices of structs can be merged rather than replaced by using the gopatch tag "merge" along with the name of a key field, named by the Patcher's
+// patch source. Each incoming element is matched to an existing element by its key and deep-patched, unmatched elements are appended, and elements
+// with `gopatch.MergeDeleteKey` set to true are removed. The PatchResult's Map field will contain keyed paths, such as `"items[id=42].qty": 5`.
+// Elements without a key are skipped, and listed in the Skipped array by their index in the patch, such as "items.0".
+//
+//     type Order struct {
+//     
+//       Items []LineItem `json:"items" gopatch:"merge,key=id"`  // Patch data for Items is merged by each element's "id".
+//     }
+//
+// Individual slice and array elements can be patched by index, using either a map of indexes such as `"addresses": {"1": {"city": "Oslo"}}` or a
+// key in dot notation such as `"addresses.1.city": "Oslo"`. Either way, the PatchResult's Map field will contain `"addresses.1.city": "Oslo"`,
+// matching MongoDB's positional dot notation. Indexes which are negative or out of range result in an error.
+//
+// Maps with string keys are replaced as a whole by default, but the gopatch tag "merge" merges the incoming keys into the existing map instead. A nil
+// value deletes its key, and values which are structs are deep-patched. The PatchResult's Map field will contain per-key paths, such as
+// `"settings.theme": "dark"`, and each key is checked against PermittedFields in the same way.
+//
+// Keys in dot notation are only expanded into fields which are patched by path: structs not tagged "replace", slices and arrays addressed by index,
+// and maps tagged "merge". Any other key containing a dot, such as `"settings.theme"` for a map which isn't merged, is left as-is and reported as
+// unknown, rather than replacing the field's whole value.
 package gopatch
\ No newline at end of file
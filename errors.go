@@ -2,9 +2,181 @@ package gopatch
 
 import(
   "errors"
+  "fmt"
+  "reflect"
+  "strconv"
+  "strings"
 )
 
-var errDestInvalid = errors.New("dest interface invalid, must be non-nil pointer to struct")
+// ErrDestInvalid is returned when the destination of a patch isn't a non-nil pointer to a struct.
+var ErrDestInvalid = errors.New("dest interface invalid, must be non-nil pointer to struct")
 
-func errFieldMissingTag(field, tag string) error { return errors.New("field `"+field+"` is missing tag `"+tag+"`")}
-func errFieldUnpermitted(field, cause string) error { return errors.New("field `"+field+"` is not permitted due to `"+cause+"`")}
\ No newline at end of file
+// Sentinel errors matching each ErrorCode, for use with `errors.Is`. For example, `errors.Is(err, gopatch.ErrUnpermitted)` reports whether a patch
+// failed because it contained an unpermitted field.
+var (
+  ErrMissingTag   = errors.New("missing tag")
+  ErrUnpermitted  = errors.New("not permitted")
+  ErrTypeMismatch = errors.New("type mismatch")
+  ErrInvalidIndex = errors.New("invalid index")
+  ErrValidation   = errors.New("validation failed")
+  ErrUnknownField = errors.New("unknown field")
+  ErrSetter       = errors.New("setter failed")
+)
+
+// ErrorCode is a machine-readable code describing why a field failed to patch.
+type ErrorCode string
+
+const (
+
+  // CodeMissingTag means the field is missing the tag of a configured field name source.
+  CodeMissingTag ErrorCode = "missing_tag"
+
+  // CodeUnpermitted means the field isn't permitted to be patched.
+  CodeUnpermitted ErrorCode = "unpermitted"
+
+  // CodeTypeMismatch means the patch value can't be converted to the field's type.
+  CodeTypeMismatch ErrorCode = "type_mismatch"
+
+  // CodeInvalidIndex means a slice or array index in the patch is negative, out of range, or not a number.
+  CodeInvalidIndex ErrorCode = "invalid_index"
+
+  // CodeValidation means the patched value failed validation.
+  CodeValidation ErrorCode = "validation"
+
+  // CodeUnknownField means a key in the patch matched no struct field.
+  CodeUnknownField ErrorCode = "unknown_field"
+
+  // CodeSetter means the field's setter method returned an error, or the setter named by its "gopatch" tag is missing or invalid.
+  CodeSetter ErrorCode = "setter"
+)
+
+var codeErrors = map[ErrorCode]error{
+  CodeMissingTag: ErrMissingTag,
+  CodeUnpermitted: ErrUnpermitted,
+  CodeTypeMismatch: ErrTypeMismatch,
+  CodeInvalidIndex: ErrInvalidIndex,
+  CodeValidation: ErrValidation,
+  CodeUnknownField: ErrUnknownField,
+  CodeSetter: ErrSetter,
+}
+
+// PatchError is returned when a field fails to patch. It holds the field's full path in the patch in dot notation, the name of the struct field,
+// a machine-readable code, and the underlying cause. A PatchError matches the sentinel error for its code when using `errors.Is`, and can be
+// retrieved from any returned error using `errors.As`.
+type PatchError struct {
+  Path  string
+  Field string
+  Code  ErrorCode
+  Cause error
+}
+
+func (e *PatchError) Error() string {
+
+  if e.Path == "" { return e.Cause.Error() }
+  return "field `"+e.Path+"`: "+e.Cause.Error()
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *PatchError) Unwrap() error {
+
+  return e.Cause
+}
+
+// Is returns whether "target" is the sentinel error for the error's code.
+func (e *PatchError) Is(target error) bool {
+
+  return target != nil && codeErrors[e.Code] == target
+}
+
+// ErrorList is returned when the Patcher is configured with CollectErrors and one or more fields fail to patch. It holds every PatchError, in the
+// order they occurred. An ErrorList matches a sentinel error or PatchError when using `errors.Is` or `errors.As` if any of its errors do.
+type ErrorList []*PatchError
+
+func (l ErrorList) Error() string {
+
+  messages := make([]string, 0, len(l))
+  for _, err := range(l) { messages = append(messages, err.Error()) }
+
+  return strconv.Itoa(len(l))+" field(s) failed to patch: "+strings.Join(messages, "; ")
+}
+
+// Is returns whether any error in the list matches "target".
+func (l ErrorList) Is(target error) bool {
+
+  for _, err := range(l) {
+    if errors.Is(err, target) { return true }
+  }
+  return false
+}
+
+// As finds the first error in the list which matches "target", and if found, sets "target" to it.
+func (l ErrorList) As(target interface{}) bool {
+
+  for _, err := range(l) {
+    if errors.As(err, target) { return true }
+  }
+  return false
+}
+
+// withPath sets the path and field name of "err" if it's a PatchError without them, such as errors raised while naming a field's results.
+func withPath(err error, path, field string) error {
+
+  if patchErr, ok := err.(*PatchError); ok && patchErr.Path == "" {
+    patchErr.Path = path
+    patchErr.Field = field
+  }
+
+  return err
+}
+
+func errFieldMissingTag(path, field, tag string) error {
+  return &PatchError{ Path: path, Field: field, Code: CodeMissingTag, Cause: fmt.Errorf("%w `%s`", ErrMissingTag, tag) }
+}
+
+func errFieldUnpermitted(path, field string, reason UnpermittedReason) error {
+  return &PatchError{ Path: path, Field: field, Code: CodeUnpermitted, Cause: fmt.Errorf("%w due to `%s`", ErrUnpermitted, reason) }
+}
+
+func errFieldIndex(path, field, index string) error {
+  return &PatchError{ Path: path, Field: field, Code: CodeInvalidIndex, Cause: fmt.Errorf("%w `%s`", ErrInvalidIndex, index) }
+}
+
+func errFieldNotConvertible(path, field string, from, to reflect.Type) error {
+  return &PatchError{ Path: path, Field: field, Code: CodeTypeMismatch, Cause: fmt.Errorf("%w, cannot convert `%v` to `%v`", ErrTypeMismatch, from, to) }
+}
+
+func errFieldSkipped(path, field string, reason SkipReason) error {
+  return &PatchError{ Path: path, Field: field, Code: CodeTypeMismatch, Cause: fmt.Errorf("%w, field skipped due to `%s`", ErrTypeMismatch, reason) }
+}
+
+func errStructInvalid(path string, cause error) error {
+  return &PatchError{ Path: path, Code: CodeValidation, Cause: cause }
+}
+
+func errFieldSetter(path, field string, cause error) error {
+  return &PatchError{ Path: path, Field: field, Code: CodeSetter, Cause: fmt.Errorf("%v: %w", ErrSetter, cause) }
+}
+
+func errFieldUnknown(path string) error {
+  return &PatchError{ Path: path, Code: CodeUnknownField, Cause: ErrUnknownField }
+}
+
+func errFieldPanic(path, field string, recovered interface{}, valueType reflect.Type) error {
+  return &PatchError{ Path: path, Field: field, Code: CodeTypeMismatch, Cause: &PanicError{ Path: path, ValueType: valueType, Panic: recovered } }
+}
+
+// PanicError is the cause of a PatchError returned when patching a field panics, such as when a malformed patch value can't be assigned to the
+// field by reflection. It holds the field's path in dot notation, the type of the value patched into it, and the value recovered from the panic.
+type PanicError struct {
+  Path      string
+  ValueType reflect.Type
+  Panic     interface{}
+}
+
+func (e *PanicError) Error() string {
+
+  valueType := "nil"
+  if e.ValueType != nil { valueType = e.ValueType.String() }
+
+  return "panicked while patching value of type `"+valueType+"`: "+fmt.Sprint(e.Panic)
+}
\ No newline at end of file
@@ -0,0 +1,18 @@
+package gopatch
+
+// BeforePatcher is implemented by structs which prepare or reject the patches made to them. If "dest", or any struct patched inside it, is a
+// BeforePatcher, BeforePatch is called before its fields are patched, with a shallow copy of its patch, in which keys in dot notation have been
+// expanded into nested patches. Changes made to the copy, such as normalizing or removing keys, are used to patch the struct. Returning an error
+// fails the patch, undoing every change made to "dest".
+type BeforePatcher interface {
+  BeforePatch(patch map[string]interface{}) error
+}
+
+// AfterPatcher is implemented by structs which update derived fields, such as an UpdatedAt time or a slug, when they're patched. If "dest", or any
+// struct patched inside it, is an AfterPatcher, AfterPatch is called once its fields are patched, before any PatchValidator, with the results of
+// the patch made to it, relative to it and without the EmbedPath. Fields it updates can be added to the results in the same way, which are then
+// included in the PatchResult returned, prepended with the EmbedPath along with the rest. Returning an error fails the patch, undoing every change
+// made to "dest", including those made by AfterPatch.
+type AfterPatcher interface {
+  AfterPatch(results *PatchResult) error
+}
\ No newline at end of file
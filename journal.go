@@ -0,0 +1,111 @@
+package gopatch
+
+import(
+  "reflect"
+)
+
+// journal records every assignment made during a patch operation, allowing the destination to be restored to its
+// original state should the operation fail part of the way through.
+type journal struct {
+  entries []journalEntry
+}
+
+type journalEntry struct {
+  target reflect.Value
+  old    reflect.Value
+}
+
+// set records the current value of "target" before assigning "value" to it.
+func (j *journal) set(target, value reflect.Value) {
+
+  j.record(target)
+  target.Set(value)
+}
+
+// record saves a copy of the current value of "target" so it can be restored later.
+func (j *journal) record(target reflect.Value) {
+
+  old := reflect.New(target.Type()).Elem()
+  old.Set(target)
+
+  j.entries = append(j.entries, journalEntry{ target: target, old: old })
+}
+
+// copyValue returns a deep copy of "v", so a dry run can patch the copy without touching "v" or anything it points to. Pointers, slices, maps and
+// interfaces are copied along with what they hold, with values reached more than once through the same pointer copied once. Unexported fields
+// can't be copied by reflection, and are shared with "v".
+func copyValue(v reflect.Value) reflect.Value {
+
+  return copyValueSeen(v, map[copiedPointer]reflect.Value{})
+}
+
+// copiedPointer identifies a pointer copied by copyValue. Pointers of different types can share an address, such as a pointer to a struct and a
+// pointer to its first field, so both are needed.
+type copiedPointer struct {
+  addr uintptr
+  t    reflect.Type
+}
+
+// copyValueSeen implements copyValue, reusing the copies in "seen" of pointers already copied, so recursive structures end.
+func copyValueSeen(v reflect.Value, seen map[copiedPointer]reflect.Value) reflect.Value {
+
+  switch v.Kind() {
+
+  case reflect.Ptr:
+    if v.IsNil() { return v }
+    key := copiedPointer{ addr: v.Pointer(), t: v.Type() }
+    if out, ok := seen[key]; ok { return out }
+    out := reflect.New(v.Type().Elem())
+    seen[key] = out
+    out.Elem().Set(copyValueSeen(v.Elem(), seen))
+    return out
+
+  case reflect.Interface:
+    if v.IsNil() { return v }
+    out := reflect.New(v.Type()).Elem()
+    out.Set(copyValueSeen(v.Elem(), seen))
+    return out
+
+  case reflect.Struct:
+    out := reflect.New(v.Type()).Elem()
+    out.Set(v)
+    for i := 0; i < v.NumField(); i++ {
+      if out.Field(i).CanSet() { out.Field(i).Set(copyValueSeen(v.Field(i), seen)) }
+    }
+    return out
+
+  case reflect.Slice:
+    if v.IsNil() { return v }
+    out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+    for i := 0; i < v.Len(); i++ {
+      out.Index(i).Set(copyValueSeen(v.Index(i), seen))
+    }
+    return out
+
+  case reflect.Array:
+    out := reflect.New(v.Type()).Elem()
+    for i := 0; i < v.Len(); i++ {
+      out.Index(i).Set(copyValueSeen(v.Index(i), seen))
+    }
+    return out
+
+  case reflect.Map:
+    if v.IsNil() { return v }
+    out := reflect.MakeMapWithSize(v.Type(), v.Len())
+    for _, k := range(v.MapKeys()) {
+      out.SetMapIndex(k, copyValueSeen(v.MapIndex(k), seen))
+    }
+    return out
+  }
+
+  return v
+}
+
+// rollback restores every recorded value, newest first, and clears the journal.
+func (j *journal) rollback() {
+
+  for i := len(j.entries)-1; i >= 0; i-- {
+    j.entries[i].target.Set(j.entries[i].old)
+  }
+  j.entries = nil
+}
\ No newline at end of file
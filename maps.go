@@ -0,0 +1,107 @@
+package gopatch
+
+import(
+  "reflect"
+  "sort"
+)
+
+// mergeMap merges "v", a map with string keys, into "fieldV", a map with string keys, key by key. A nil value deletes its key, map values which
+// are structs are deep-patched, and any other values are converted and set. Results are recorded with the key in the path, such as
+// `settings.theme`. If either map doesn't have string keys, the field is skipped.
+func (p Patcher) mergeMap(results *PatchResult, dest reflect.StructField, fieldV, v reflect.Value, permitted permissions, state *patchState) error {
+
+  if !v.IsValid() || v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String { return p.skipField(state.path, dest.Name, SkipIncompatibleType, state) }
+  if fieldV.Type().Key().Kind() != reflect.String { return p.skipField(state.path, dest.Name, SkipUnsupportedKind, state) }
+
+  fieldName, mapName, err := p.resultNames(dest)
+  if err != nil { return err }
+
+  // Work on a copy of the map, so the field can be restored by assigning the original.
+  mapT := fieldV.Type()
+  merged := reflect.MakeMapWithSize(mapT, fieldV.Len())
+  for _, k := range(fieldV.MapKeys()) {
+    merged.SetMapIndex(k, fieldV.MapIndex(k))
+  }
+
+  // Expand keys in dot notation into patches of struct values, then merge keys in order, so results are predictable.
+  if m, ok := v.Interface().(map[string]interface{}); ok { v = reflect.ValueOf(expandPaths(m, expandsElem(mapT))) }
+  keys := make([]string, 0, v.Len())
+  for _, k := range(v.MapKeys()) { keys = append(keys, k.String()) }
+  sort.Strings(keys)
+
+  changed := false
+  for _, key := range(keys) {
+
+    val := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+    if val.Kind() == reflect.Interface { val = val.Elem() }
+    k := reflect.ValueOf(key).Convert(mapT.Key())
+    existing := merged.MapIndex(k)
+
+    // Deep-patch struct values, starting from the existing value if there is one.
+    elem := reflect.New(mapT.Elem()).Elem()
+    if existing.IsValid() { elem.Set(existing) }
+    if val.IsValid() && isPatchedByPath(elem, val.Interface(), fieldTag{}) && elem.Kind() != reflect.Slice && elem.Kind() != reflect.Array {
+
+      target := elem
+      if target.Kind() == reflect.Ptr {
+        if target.IsNil() { target.Set(reflect.New(target.Type().Elem())) }
+        target = target.Elem()
+      }
+
+      prev := state.enter(key)
+      deep, err := p.patch(target.Addr().Interface(), val.Interface().(map[string]interface{}), permitted.child(key), state)
+      state.path = prev
+      if err != nil { return err }
+      if p.skipUnchanged(state) && len(deep.Changes) == 0 { continue }
+
+      merged.SetMapIndex(k, elem)
+      appendResults(results, deep, fieldName+"."+key, mapName+"."+key)
+      changed = true
+      continue
+    }
+
+    // Skip key or error if it isn't permitted by the array.
+    if !permitted.allows(key) {
+      if err := p.unpermit(state.pathTo(key), dest.Name, UnpermittedByArray, state); err != nil { return err }
+      continue
+    }
+
+    // Delete the key if the value is nil.
+    var old interface{}
+    if existing.IsValid() { old = existing.Interface() }
+    if !val.IsValid() {
+      if !existing.IsValid() { continue }
+
+      merged.SetMapIndex(k, reflect.Value{})
+      results.Fields = append(results.Fields, fieldName+"."+key)
+      results.Map[mapName+"."+key] = nil
+      results.Changes = append(results.Changes, Change{ Path: mapName+"."+key, Old: old, New: nil })
+      changed = true
+      continue
+    }
+
+    // Set any other value, converted to the map's element type.
+    prev := state.enter(key)
+    converted, ok, err := p.convert(mapT.Elem(), val, permitted.child(key), state)
+    state.path = prev
+    if err != nil { return err }
+    if !ok {
+      if err := p.skipField(state.pathTo(key), dest.Name, SkipIncompatibleType, state); err != nil { return err }
+      continue
+    }
+    if p.skipUnchanged(state) && existing.IsValid() && equalValues(existing, converted) { continue }
+
+    merged.SetMapIndex(k, converted)
+    results.Fields = append(results.Fields, fieldName+"."+key)
+    results.Map[mapName+"."+key] = val.Interface()
+    results.Changes = append(results.Changes, Change{ Path: mapName+"."+key, Old: old, New: converted.Interface() })
+    changed = true
+  }
+
+  if !changed { return nil }
+  if err := p.validate(dest, merged, state.path); err != nil { return err }
+
+  state.journal.set(fieldV, merged)
+
+  return nil
+}
\ No newline at end of file
@@ -13,10 +13,29 @@ type PatchResult struct {
   // configured source.
   Fields []string
 
-  // Unpermitted is an array of fields in dot notation which were found to
-  // be unpermitted for patching.
+  // Unpermitted is an array of fields in the patch which were found to be
+  // unpermitted for patching, at any level of the structure. Each is the
+  // field's absolute path in dot notation, prepended with the Patcher's
+  // configured EmbedPath.
   Unpermitted []string
 
+  // UnpermittedReasons maps each path in Unpermitted to the reason the
+  // field was unpermitted.
+  UnpermittedReasons map[string]UnpermittedReason
+
+  // Unknown is an array of keys in the patch which matched no field at
+  // any level of the structure, such as typos or removed fields. Each is
+  // the key's absolute path in dot notation, prepended with the Patcher's
+  // configured EmbedPath. The array is sorted.
+  Unknown []string
+
+  // Skipped is an array of fields in the patch which were skipped, at any
+  // level of the structure, because their value couldn't be patched into
+  // them, such as a string patched into a bool field. Each holds the
+  // field's absolute path in dot notation, prepended with the Patcher's
+  // configured EmbedPath, and the reason it was skipped.
+  Skipped []Skip
+
   // Map is a map of the successful patches made to the struct. This is
   // most useful as update data for a corresponding database row or
   // document. When a struct field is encountered and the field's
@@ -26,4 +45,92 @@ type PatchResult struct {
   // be replaced with the patch data, with any unaccounted-for values
   // being initialized to their zero values.
   Map map[string]interface{}
-}
\ No newline at end of file
+
+  // Changes is a list of every field changed by the patch, including
+  // fields inside embedded structs. Each change's path is named the same
+  // way as the keys of Map. If the "gopatch" tag's value is set to
+  // "replace", the embedded struct is recorded as a single change.
+  Changes []Change
+}
+
+// Change describes a single field changed by a patch operation.
+type Change struct {
+
+  // Path is the changed field's path in dot notation.
+  Path string
+
+  // Old is the field's value before the patch.
+  Old interface{}
+
+  // New is the field's value after the patch, after any conversion made
+  // by Updaters.
+  New interface{}
+}
+
+// Skip describes a field in the patch which was skipped because its value
+// couldn't be patched into it.
+type Skip struct {
+
+  // Path is the skipped field's absolute path in dot notation, prepended
+  // with the Patcher's configured EmbedPath.
+  Path string
+
+  // Reason is why the field was skipped.
+  Reason SkipReason
+}
+
+// SkipReason describes why a field was skipped.
+type SkipReason string
+
+const (
+
+  // SkipIncompatibleType means no conversion exists from the patch
+  // value's type to the field's type.
+  SkipIncompatibleType SkipReason = "incompatible type"
+
+  // SkipUnsupportedKind means the field is of a kind which can't be
+  // patched, such as a channel or function.
+  SkipUnsupportedKind SkipReason = "unsupported kind"
+
+  // SkipNotAddressable means the field couldn't be addressed in order to
+  // deep-patch it.
+  SkipNotAddressable SkipReason = "not addressable"
+
+  // SkipMissingKey means an element patched into a slice with the
+  // "merge" gopatch tag doesn't hold its key, or holds a nil key, so it
+  // can't be matched to an element or reported under a keyed path.
+  SkipMissingKey SkipReason = "missing key"
+)
+
+// UnpermittedReason describes why a field was unpermitted.
+type UnpermittedReason string
+
+const (
+
+  // UnpermittedByTag means the field's "gopatch" tag is set to "-".
+  UnpermittedByTag UnpermittedReason = "gopatch tag"
+
+  // UnpermittedByOnce means the field's "gopatch" tag is set to "once",
+  // and the field no longer holds its zero value.
+  UnpermittedByOnce UnpermittedReason = "once"
+
+  // UnpermittedByImmutable means the field's "gopatch" tag is set to
+  // "immutable", and the patch would change its value.
+  UnpermittedByImmutable UnpermittedReason = "immutable"
+
+  // UnpermittedByArray means the field isn't in the Patcher's configured
+  // PermittedFields array.
+  UnpermittedByArray UnpermittedReason = "permitted array"
+
+  // UnpermittedByRole means the field's "gopatch" tag requires a role the
+  // caller doesn't have.
+  UnpermittedByRole UnpermittedReason = "role"
+
+  // UnpermittedByAuthorizer means the Patcher's configured Authorizer
+  // didn't allow the field.
+  UnpermittedByAuthorizer UnpermittedReason = "authorizer"
+
+  // UnpermittedByTransition means the field's "gopatch" tag doesn't allow
+  // its value to change from the current value to the patched one.
+  UnpermittedByTransition UnpermittedReason = "transition"
+)
\ No newline at end of file
@@ -1,221 +1,593 @@
 package gopatch
 
 import(
+  "context"
+  "fmt"
   "reflect"
+  "sort"
+  "strconv"
   "strings"
+  "unsafe"
 )
 
 // Patcher is a configurable structure patcher.
 type Patcher struct {
-  config  PatcherConfig
+  config      PatcherConfig
+  permissions permissions
+  err         error
 }
 
-// New creates a new Patcher instance with the specified configuration. See `patcher_config.go`.
+// patchState holds the state of a single patch operation as it recurses through the destination.
+type patchState struct {
+  ctx       context.Context
+  journal   journal
+  replacing bool
+  dryRun    bool
+  scratch   bool
+  path      string
+  collect   bool
+  errors    ErrorList
+  unknown   []string
+  skipped   []Skip
+  blocked   []string
+  reasons   map[string]UnpermittedReason
+  roles     map[string]bool
+}
+
+// fail records "err" and returns nil if collecting errors and "err" is a PatchError, allowing the patch to continue. Otherwise, "err" is returned.
+func (s *patchState) fail(err error) error {
+
+  patchErr, ok := err.(*PatchError)
+  if !s.collect || !ok { return err }
+
+  s.errors = append(s.errors, patchErr)
+  return nil
+}
+
+// hasRole returns whether the caller has any of "roles", separated by "|".
+func (s *patchState) hasRole(roles string) bool {
+
+  for _, role := range(strings.Split(roles, "|")) {
+    if s.roles[strings.TrimSpace(role)] { return true }
+  }
+
+  return false
+}
+
+// pathTo returns the state's path extended by "name" in dot notation.
+func (s *patchState) pathTo(name string) string {
+
+  if s.path == "" { return name }
+  return s.path+"."+name
+}
+
+// enter extends the state's path with "name" in dot notation, returning the previous path so it can be restored.
+func (s *patchState) enter(name string) string {
+
+  prev := s.path
+  s.path = s.pathTo(name)
+
+  return prev
+}
+
+// New creates a new Patcher instance with the specified configuration. See `patcher_config.go`. The PermittedFields patterns are compiled once here.
+// If any of them are invalid, such as "profile..motto" or "addr[", every patch made with the Patcher returns the error. Use NewPatcher to check
+// the configuration up front instead.
 func New(config PatcherConfig) *Patcher {
 
+  permissions, err := compilePermissions(config.PermittedFields, config.DenyByDefault)
+
   return &Patcher{
     config: config,
+    permissions: permissions,
+    err: err,
   }
 }
 
+// NewPatcher creates a new Patcher instance like New, but returns an error if the configuration is invalid, such as when any of the
+// PermittedFields patterns are invalid.
+func NewPatcher(config PatcherConfig) (*Patcher, error) {
+
+  p := New(config)
+  if p.err != nil { return nil, p.err }
+
+  return p, nil
+}
+
 // Patch performs a patch operation on "dest", using the data in "patch". Patch returns a PatchResult if successful, or an error if not. Patch can
 // also patch embedded structs and pointers to embedded structs. If a patch exists for a nil embedded struct pointer, the pointer will be assigned a
-// new zero-value struct before it is patched.
+// new zero-value struct before it is patched. Patching is all-or-nothing: if an error is returned, every change already made to "dest", including
+// any allocated embedded struct pointers, is undone before Patch returns.
 func (p Patcher) Patch(dest interface{}, patch map[string]interface{}) (*PatchResult, error) {
 
+  return p.run(context.Background(), dest, patch, false)
+}
+
+// Preview performs a dry run of a patch operation on "dest", using the data in "patch". Preview goes through the same steps as Patch and returns
+// the same PatchResult or error, but patches a deep copy of "dest", which is never written to. This is useful for showing which fields a patch
+// would change before it is applied. Lifecycle hooks and PatchValidators are called on the copy, while setters aren't called at all: the fields
+// they would set are set directly on the copy instead, so the results hold the values before any changes a setter would make.
+func (p Patcher) Preview(dest interface{}, patch map[string]interface{}) (*PatchResult, error) {
+
+  return p.run(context.Background(), dest, patch, true)
+}
+
+// PatchAs performs a patch operation like Patch, on behalf of a caller with "roles". Fields with the "gopatch" tag option "roles", such as
+// `gopatch:"roles=admin|support"`, are only permitted if the caller has at least one of the roles listed. Patch permits them to no one.
+func (p Patcher) PatchAs(dest interface{}, patch map[string]interface{}, roles ...string) (*PatchResult, error) {
+
+  return p.run(WithRoles(context.Background(), roles...), dest, patch, false)
+}
+
+// PreviewAs performs a dry run of a patch operation like Preview, on behalf of a caller with "roles". See PatchAs.
+func (p Patcher) PreviewAs(dest interface{}, patch map[string]interface{}, roles ...string) (*PatchResult, error) {
+
+  return p.run(WithRoles(context.Background(), roles...), dest, patch, true)
+}
+
+// PatchContext performs a patch operation like Patch, passing "ctx" to the configured Authorizer for each field. Roles added to "ctx" with
+// WithRoles are checked as they are by PatchAs. If "ctx" is cancelled or times out, the patch stops and returns its error, undoing every change
+// already made to "dest".
+func (p Patcher) PatchContext(ctx context.Context, dest interface{}, patch map[string]interface{}) (*PatchResult, error) {
+
+  return p.run(ctx, dest, patch, false)
+}
+
+// PreviewContext performs a dry run of a patch operation like Preview, using "ctx" as PatchContext does.
+func (p Patcher) PreviewContext(ctx context.Context, dest interface{}, patch map[string]interface{}) (*PatchResult, error) {
+
+  return p.run(ctx, dest, patch, true)
+}
+
+func (p Patcher) run(ctx context.Context, dest interface{}, patch map[string]interface{}, dryRun bool) (*PatchResult, error) {
+
+  // Error on invalid configuration.
+  if p.err != nil { return nil, p.err }
+
   // Error on invalid dest.
   if reflect.ValueOf(dest).Kind() != reflect.Ptr ||
     reflect.ValueOf(dest).IsNil() ||
     reflect.ValueOf(dest).Elem().Kind() != reflect.Struct {
     
-    return nil, errDestInvalid
+    return nil, ErrDestInvalid
+  }
+
+  // Patch with a fresh state, undoing every change made to dest if any part of the patch fails or this is a dry run.
+  roles := rolesFromContext(ctx)
+  state := patchState{ ctx: ctx, collect: p.config.CollectErrors, reasons: map[string]UnpermittedReason{}, roles: make(map[string]bool, len(roles)) }
+  for _, role := range(roles) {
+    state.roles[role] = true
+  }
+
+  // Dry runs patch a deep copy of dest, so they never write to it or anything it points to.
+  if dryRun {
+    state.dryRun = true
+    dest = copyValue(reflect.ValueOf(dest)).Interface()
+  }
+
+  results, err := p.patch(dest, patch, p.permissions, &state)
+  if err == nil && len(state.errors) > 0 { err = state.errors }
+  if err != nil {
+    state.journal.rollback()
+    return nil, err
+  }
+
+  // Results are gathered relative to dest, as seen by its hooks and validator, then prepended with the embed path.
+  if p.config.EmbedPath != "" {
+    embedded := &PatchResult{
+      Fields: make([]string, 0, len(results.Fields)),
+      Map: make(map[string]interface{}, len(results.Map)),
+      Changes: make([]Change, 0, len(results.Changes)),
+    }
+    appendResults(embedded, results, p.config.EmbedPath, p.config.EmbedPath)
+    results.Fields, results.Map, results.Changes = embedded.Fields, embedded.Map, embedded.Changes
+  }
+
+  // Unpermitted fields are gathered from every level of the patch, with absolute paths including the embed path.
+  results.Unpermitted = make([]string, 0, len(state.blocked))
+  results.UnpermittedReasons = make(map[string]UnpermittedReason, len(state.blocked))
+  for _, path := range(state.blocked) {
+    results.Unpermitted = append(results.Unpermitted, p.absolutePath(path))
+    results.UnpermittedReasons[p.absolutePath(path)] = state.reasons[path]
   }
-  return p.patch(dest, patch, p.config.PermittedFields, true)
+
+  // Unknown keys are gathered from every level of the patch, with absolute paths too.
+  results.Unknown = make([]string, 0, len(state.unknown))
+  for _, path := range(state.unknown) {
+    results.Unknown = append(results.Unknown, p.absolutePath(path))
+  }
+  sort.Strings(results.Unknown)
+
+  // Skipped fields are gathered from every level of the patch, with absolute paths too.
+  results.Skipped = make([]Skip, 0, len(state.skipped))
+  for _, skip := range(state.skipped) {
+    results.Skipped = append(results.Skipped, Skip{ Path: p.absolutePath(skip.Path), Reason: skip.Reason })
+  }
+
+  return results, nil
+}
+
+// absolutePath returns "path", a path in the patch, prepended with the configured EmbedPath, if any.
+func (p Patcher) absolutePath(path string) string {
+
+  if p.config.EmbedPath == "" { return path }
+  return p.config.EmbedPath+"."+path
 }
 
-func (p Patcher) patch(dest interface{}, patch map[string]interface{}, permitted []string, root bool) (*PatchResult, error) {
+func (p Patcher) patch(dest interface{}, patch map[string]interface{}, permitted permissions, state *patchState) (*PatchResult, error) {
   
   // Get the actual struct data from the pointer and its type data.
   valueOfDest := reflect.ValueOf(dest).Elem()
   typeOfDest := valueOfDest.Type()
+
+  // Expand any keys in dot notation into nested patches for the fields which are patched by path.
+  expand := p.expandsField(valueOfDest)
+  patch = expandPaths(patch, expand)
+
+  // Let the struct prepare or reject its patch, using a copy so the caller's patch is left untouched. Anything the hook changes in the struct is
+  // recorded so it can be undone.
+  if before, ok := dest.(BeforePatcher); ok && !state.scratch {
+
+    prepared := make(map[string]interface{}, len(patch))
+    for k, v := range(patch) { prepared[k] = v }
+
+    state.journal.record(valueOfDest)
+    if err := before.BeforePatch(prepared); err != nil { return nil, err }
+    patch = expandPaths(prepared, expand)
+  }
   
   // Initialize and allocate space for the results.
   results := PatchResult{
     Fields: make([]string, 0, len(patch)*100),
-    Unpermitted: make([]string, 0, len(patch)*100),
     Map: make(map[string]interface{}, len(patch)*100),
+    Changes: make([]Change, 0, len(patch)),
   }
 
   // For each field in the destination struct,
+  matched := make(map[string]bool, len(patch))
   for i := 0; i < typeOfDest.NumField(); i++ {
 
+    // Stop if the patch was cancelled.
+    if err := state.ctx.Err(); err != nil { return nil, err }
+
     fieldT := typeOfDest.Field(i)
     fieldV := valueOfDest.Field(i)
 
-    // Skip this field if it can't be set.
-    if !valueOfDest.Field(i).CanSet() {
+    // Get the name of the field to check for in the patch map, defaulting to the field's struct field name.
+    fieldName, err := p.patchName(fieldT)
+    if err != nil {
+      if !fieldV.CanSet() { continue }
+      if err := state.fail(withPath(err, state.pathTo(fieldT.Name), fieldT.Name)); err != nil { return nil, err }
       continue
     }
 
-    // Get the name of the field to check for in the patch map, defaulting to the field's struct field name.
-    fieldName := fieldT.Name
-    if p.config.PatchSource != "" && p.config.PatchSource != "struct" {
-      
-      testFieldName := fieldT.Tag.Get(p.config.PatchSource)
-      if testFieldName != "" {
-        fieldName = testFieldName
-      } else if p.config.PatchErrors {
-        return nil, errFieldMissingTag(fieldName, p.config.PatchSource)
-      }
+    // Get the patch value based on the fieldName, skipping the field if there is none.
+    val, ok := patch[fieldName]
+    if !ok { continue }
+
+    // Find the field's setter, if any.
+    setter, err := findSetter(valueOfDest, fieldT)
+    if err != nil {
+      matched[fieldName] = true
+      if err := state.fail(errFieldSetter(state.pathTo(fieldName), fieldT.Name, err)); err != nil { return nil, err }
+      continue
     }
 
-    // Get the patch value based on the fieldName.
-    if val, ok := patch[fieldName]; ok {
+    // Skip this field if it can't be set, unless it has a setter. Unexported fields with setters are read through a view of the field's memory, but
+    // are only ever written by their setter.
+    if !fieldV.CanSet() {
+      if setter == nil { continue }
+      fieldV = reflect.NewAt(fieldV.Type(), unsafe.Pointer(fieldV.UnsafeAddr())).Elem()
+    }
 
-      // Check that the field isn't unpermitted by tag. Doing this before checking the permitted list placed priority on the tag.
-      if fieldT.Tag.Get("gopatch") == "-" {
-        if p.config.UnpermittedErrors { return nil, errFieldUnpermitted(fieldName, "gopatch tag") }
-        results.Unpermitted = append(results.Unpermitted, fieldName)
-        continue
-      }
+    // Patch the field with the patch value.
+    matched[fieldName] = true
+    if err := p.patchField(&results, fieldT, fieldV, setter, fieldName, val, permitted, state); err != nil { return nil, err }
+  }
 
-      // Check that the field is permitted by the array, or the permitted array is empty.
-      if p.config.PermittedFields != nil && len(p.config.PermittedFields) > 0 {
-
-        allowed := false
-        for _, permit := range(permitted) {
-
-          // Break if it's an asterisk. It's auto-permitted.
-          if permit == "*" {
-            allowed = true
-            break
-          }
-
-          // Permit if exact match or "match.*".
-          if permit == fieldName ||  permit == fieldName+".*" {
-            allowed = true
-            break
-          }
-        }
-
-        // Skip field or error if it wasn't permitted.
-        if !allowed {
-          if p.config.UnpermittedErrors { return nil, errFieldUnpermitted(fieldName, "permitted array") }
-          results.Unpermitted = append(results.Unpermitted, fieldName)
-          continue
-        }
-      }
+  // Record keys which matched no field as unknown, or error if rejecting them.
+  unknown := make([]string, 0, len(patch)-len(matched))
+  for key := range(patch) {
+    if !matched[key] { unknown = append(unknown, state.pathTo(key)) }
+  }
+  sort.Strings(unknown)
+  for _, path := range(unknown) {
+    if p.config.RejectUnknownFields {
+      if err := state.fail(errFieldUnknown(path)); err != nil { return nil, err }
+      continue
+    }
+    state.unknown = append(state.unknown, path)
+  }
 
-      v := reflect.ValueOf(val)
+  // Let the struct update derived fields now that its fields are patched, recording it first so the updates can be undone.
+  if after, ok := dest.(AfterPatcher); ok && !state.scratch {
+    state.journal.record(valueOfDest)
+    if err := after.AfterPatch(&results); err != nil { return nil, err }
+  }
 
-      // Easily assign the value if both ends' kinds are the same
-      if fieldV.Kind() == v.Kind() && fieldV.Kind() != reflect.Map {
-        fieldV.Set(v)
-        
-        // Add data about the successful update to the results.
-        if err := p.saveToResults(&results, fieldT, val, root); err != nil { return nil, err }
+  // Let the struct check invariants across its fields now that they're patched.
+  if validator, ok := dest.(PatchValidator); ok && !state.scratch {
+    if err := validator.ValidatePatch(&results); err != nil {
+      if err := state.fail(errStructInvalid(state.path, err)); err != nil { return nil, err }
+    }
+  }
 
-        // Next!
-        continue
-      }
+  return &results, nil
+}
 
-      // Check updater functions for a match.
-      updateSuccess := false
-      for _, updater := range Updaters {
+// patchField patches the field "fieldV" with "val", which was found in the patch under "fieldName", adding data about the update to the results.
+// Any panic while patching the field is recovered and returned as a PatchError caused by a PanicError. If collecting errors, any PatchError is
+// recorded in the state instead of being returned.
+func (p Patcher) patchField(results *PatchResult, fieldT reflect.StructField, fieldV reflect.Value, setter *fieldSetter, fieldName string, val interface{}, permitted permissions, state *patchState) (err error) {
+
+  // Track the field's path while patching it, restoring the parent's path when done.
+  prev := state.enter(fieldName)
+  path := state.path
+  defer func() {
+    if r := recover(); r != nil { err = errFieldPanic(path, fieldT.Name, r, reflect.TypeOf(val)) }
+    err = state.fail(withPath(err, path, fieldT.Name))
+    state.path = prev
+  }()
+
+  tag := parseTag(fieldT)
+
+  // Check that the field isn't unpermitted by tag. Doing this before checking the permitted list placed priority on the tag.
+  if tag.has("-") {
+    return p.unpermit(path, fieldT.Name, UnpermittedByTag, state)
+  }
 
-        // Try to update, breaking if successful
-        if updateSuccess = updater(fieldV, v); updateSuccess { break }
-      }
-      if updateSuccess {
+  // Check that a write-once field hasn't been written, and that an immutable field isn't being changed.
+  if tag.has("once") && !fieldV.IsZero() {
+    return p.unpermit(path, fieldT.Name, UnpermittedByOnce, state)
+  }
+  if tag.has("immutable") {
+    newV, ok := p.converted(fieldT.Type, val, state)
+    if !ok || !equalValues(fieldV, newV) { return p.unpermit(path, fieldT.Name, UnpermittedByImmutable, state) }
+  }
 
-        // Add data about the successful update to the results.
-        if err := p.saveToResults(&results, fieldT, val, root); err != nil { return nil, err }
+  // Check that the caller has one of the roles the tag requires, if any.
+  if roles, ok := tag.option("roles"); ok && !state.hasRole(roles) {
+    return p.unpermit(path, fieldT.Name, UnpermittedByRole, state)
+  }
 
-        // Next!
-        continue
-      }
+  // Check that the change is one of the transitions the tag allows, if any.
+  if transitions, ok := tag.option("transitions"); ok {
+    newV, ok := p.converted(fieldT.Type, val, state)
+    if !ok || !allowsTransition(transitions, formatValue(fieldV), formatValue(newV)) {
+      return p.unpermit(path, fieldT.Name, UnpermittedByTransition, state)
+    }
+  }
 
-      // Dereference the value if it's a pointer.
-      if fieldV.Kind() == reflect.Ptr {
+  // Skip field or error if it isn't permitted by the array or the "public" tag, which permits the tagged field alone. Fields patched by path, such
+  // as structs and slices patched from a map, are also permitted if the array permits any path inside them, or they contain any public field, as
+  // their own fields and elements are then checked in turn.
+  inner := permitted.child(fieldName)
+  if tag.has("public") { inner = inner.grant() }
+  if !inner.permitted() && !(isPatchedByPath(fieldV, val, tag) && !tag.has("replace") && (inner.within() || containsPublic(fieldT.Type))) {
+    return p.unpermit(path, fieldT.Name, UnpermittedByArray, state)
+  }
 
-        // Ensure it's not nil, initializing to zero-value if needed.
-        if fieldV.IsNil() {
-          fieldV.Set(reflect.New(fieldV.Type().Elem()))
-        }
+  // Ask the authorizer, if any, last of all.
+  if p.config.Authorizer != nil && !state.scratch {
+    allowed, err := p.config.Authorizer.Allow(state.ctx, path, fieldT, fieldV.Interface(), val)
+    if err != nil { return err }
+    if !allowed { return p.unpermit(path, fieldT.Name, UnpermittedByAuthorizer, state) }
+  }
 
-        fieldV = fieldV.Elem()
-      }
+  // Fields with setters are set as a whole by calling the setter, so they must be permitted as a whole, not just by paths inside them.
+  if setter != nil {
+    if !inner.permitted() { return p.unpermit(path, fieldT.Name, UnpermittedByArray, state) }
+    return p.set(results, fieldT, fieldV, setter, val, inner, state)
+  }
 
-      // If the value is a struct, attempt to deep-patch it.
-      if fieldV.Kind() == reflect.Struct {
+  v := reflect.ValueOf(val)
 
-        // If the map field's kind isn't map[string]interface{}, skip it.
-        if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.Interface { continue }
-        
-        // If the gopatch tag specifies "replace", reset the current field value to its zero value.
-        replace := fieldT.Tag.Get("gopatch") == "replace"
-        if replace {
-          fieldV.Set(reflect.Zero(fieldT.Type))
-        }
+  // Merge maps key by key if the gopatch tag specifies "merge".
+  if fieldV.Kind() == reflect.Map && tag.has("merge") {
+    return p.mergeMap(results, fieldT, fieldV, v, inner, state)
+  }
 
-        // Patch the field, even if it was reset, by recursion.
-        if !fieldV.CanAddr() { continue }
-        deep, err := p.patch(fieldV.Addr().Interface(), val.(map[string]interface{}), getPermittedInEmbedded(permitted, fieldName), false)
+  // Replace slices and arrays element by element, converting each element.
+  if fieldV.Kind() == reflect.Slice || fieldV.Kind() == reflect.Array {
 
-        // If an error occurred while deep-patching, bubble up immediately.
-        if err != nil { return nil, err }
+    // Merge slices of structs by key instead if the gopatch tag specifies "merge" and a key.
+    if key, hasKey := tag.option("key"); hasKey && tag.has("merge") && fieldV.Kind() == reflect.Slice {
+      return p.mergeSlice(results, fieldT, fieldV, v, key, inner, state)
+    }
 
-        // Merge deep-patched results into the current results.
-        if err := p.mergeResults(&results, deep, fieldT, replace, root); err != nil { return nil, err }
-      }
+    // Patch individual elements if the patch is a map of indexes.
+    if m, isMap := val.(map[string]interface{}); isMap {
+      return p.patchIndexes(results, fieldT, fieldV, m, inner, state)
     }
-  }
 
-  return &results, nil
-}
+    // The field is permitted, so the new elements replacing its own are built with everything inside them permitted.
+    newV, ok, err := p.convertSlice(fieldV.Type(), v, inner.grantAll(), state)
+    if err != nil { return err }
+    if !ok { return p.skipField(path, fieldT.Name, SkipIncompatibleType, state) }
 
-func (p *Patcher) saveToResults(r *PatchResult, dest reflect.StructField, patch interface{}, root bool) error {
+    // Assign and add data about the successful update to the results, using the converted slice.
+    return p.assign(results, fieldT, fieldV, newV, newV.Interface(), state)
+  }
 
-  // Get a field name for the fields array.
-  fieldName := dest.Name
-  if p.config.UpdatedFieldSource != "" && p.config.UpdatedFieldSource != "struct" {
+  // Easily assign the value if both ends' kinds are the same, converting it to the field's type if they differ, such as for named types.
+  if fieldV.Kind() == v.Kind() && fieldV.Kind() != reflect.Map {
     
-    testFieldName := dest.Tag.Get(p.config.UpdatedFieldSource)
-    if testFieldName != "" {
-      fieldName = testFieldName
-    } else if p.config.UpdatedFieldErrors {
-      return errFieldMissingTag(fieldName, p.config.UpdatedFieldSource)
+    if !v.Type().AssignableTo(fieldV.Type()) {
+      if !v.Type().ConvertibleTo(fieldV.Type()) { return errFieldNotConvertible(path, fieldT.Name, v.Type(), fieldV.Type()) }
+      v = v.Convert(fieldV.Type())
     }
+
+    // Assign and add data about the successful update to the results.
+    return p.assign(results, fieldT, fieldV, v, val, state)
   }
 
-  // Prepend the embed path if this is root and there is an embed path.
-  if root && p.config.EmbedPath != "" { fieldName = p.config.EmbedPath+"."+fieldName }
+  // Check updater functions for a match. Updaters work on a copy of the field so a failed patch can be undone.
+  updateSuccess := false
+  scratch := reflect.New(fieldV.Type()).Elem()
+  scratch.Set(fieldV)
+  for _, updater := range Updaters {
 
-  // Append.
-  r.Fields = append(r.Fields, fieldName)
+    // Try to update, breaking if successful
+    if updateSuccess = updater(scratch, v); updateSuccess { break }
+  }
+  if updateSuccess {
 
-  // Get a field name for the map.
-  fieldName = dest.Name
-  if p.config.UpdatedMapSource != "" && p.config.UpdatedMapSource != "struct" {
-    
-    testFieldName := dest.Tag.Get(p.config.UpdatedMapSource)
-    if testFieldName != "" {
-      fieldName = testFieldName
-    } else if p.config.UpdatedMapErrors {
-      return errFieldMissingTag(fieldName, p.config.UpdatedMapSource)
+    // Assign and add data about the successful update to the results.
+    return p.assign(results, fieldT, fieldV, scratch, val, state)
+  }
+
+  // Only structs and pointers to structs remain, which are deep-patched from map[string]interface{}. Skip anything else.
+  structT := fieldV.Type()
+  if structT.Kind() == reflect.Ptr { structT = structT.Elem() }
+  if structT.Kind() != reflect.Struct {
+    if isUnsupported(structT.Kind()) { return p.skipField(path, fieldT.Name, SkipUnsupportedKind, state) }
+    return p.skipField(path, fieldT.Name, SkipIncompatibleType, state)
+  }
+  m, ok := val.(map[string]interface{})
+  if !ok { return p.skipField(path, fieldT.Name, SkipIncompatibleType, state) }
+
+  // Dereference the value if it's a pointer.
+  ptrV := reflect.Value{}
+  if fieldV.Kind() == reflect.Ptr {
+
+    // Ensure it's not nil, initializing to zero-value if needed.
+    if fieldV.IsNil() {
+      state.journal.set(fieldV, reflect.New(fieldV.Type().Elem()))
+      ptrV = fieldV
     }
+
+    fieldV = fieldV.Elem()
   }
 
-  // Prepend the embed path if this is root and there is an embed path.
-  if root && p.config.EmbedPath != "" { fieldName = p.config.EmbedPath+"."+fieldName }
+  // If the gopatch tag specifies "replace", reset the current field value to its zero value.
+  old := fieldV.Interface()
+  replace := tag.has("replace")
+  if replace {
+    state.journal.set(fieldV, reflect.Zero(fieldV.Type()))
+  }
+
+  // Patch the field, even if it was reset, by recursion. A replaced struct is compared as a whole, not field by field.
+  if !fieldV.CanAddr() { return p.skipField(path, fieldT.Name, SkipNotAddressable, state) }
+  replacing := state.replacing
+  state.replacing = replacing || replace
+  deep, err := p.patch(fieldV.Addr().Interface(), m, inner, state)
+  state.replacing = replacing
+
+  // If an error occurred while deep-patching, bubble up immediately.
+  if err != nil { return err }
+
+  // Skip the struct if skipping unchanged fields and nothing changed, releasing any pointer allocated for it.
+  if p.skipUnchanged(state) && ((replace && equalValues(reflect.ValueOf(old), fieldV)) || (!replace && len(deep.Changes) == 0)) {
+    if ptrV.IsValid() { state.journal.set(ptrV, reflect.Zero(ptrV.Type())) }
+    return nil
+  }
+
+  // Merge deep-patched results into the current results.
+  return p.mergeResults(results, deep, fieldT, replace, old, fieldV.Interface())
+}
 
-  // Add to map.
-  r.Map[fieldName] = patch
+// assign sets "fieldV" to "newV" and adds data about the update to the results. If the Patcher is configured to skip unchanged fields and both
+// values are equal, the field is left untouched and the results are unaffected.
+func (p *Patcher) assign(r *PatchResult, dest reflect.StructField, fieldV, newV reflect.Value, patch interface{}, state *patchState) error {
 
+  old := fieldV.Interface()
+  if p.skipUnchanged(state) && equalValues(fieldV, newV) { return nil }
+  if err := p.validate(dest, newV, state.path); err != nil { return err }
+
+  state.journal.set(fieldV, newV)
+
+  return p.saveToResults(r, dest, patch, old, fieldV.Interface())
+}
+
+// unpermit records the field at "path" as unpermitted for "reason", or returns an error if the Patcher is configured with UnpermittedErrors.
+func (p *Patcher) unpermit(path, field string, reason UnpermittedReason, state *patchState) error {
+
+  if p.config.UnpermittedErrors { return state.fail(errFieldUnpermitted(path, field, reason)) }
+
+  state.blocked = append(state.blocked, path)
+  state.reasons[path] = reason
   return nil
 }
 
-func (p *Patcher) mergeResults(top, deep *PatchResult, dest reflect.StructField, replace, root bool) error {
+// skipField records the field at "path" as skipped for "reason", or returns an error if the Patcher is configured with StrictTypes.
+func (p *Patcher) skipField(path, field string, reason SkipReason, state *patchState) error {
+
+  if p.config.StrictTypes { return state.fail(errFieldSkipped(path, field, reason)) }
+
+  state.skipped = append(state.skipped, Skip{ Path: path, Reason: reason })
+  return nil
+}
+
+// skipUnchanged reports whether unchanged fields should be left out. Fields inside a replaced struct are never left out, as the struct's patch
+// data is reported exactly as presented.
+func (p *Patcher) skipUnchanged(state *patchState) bool {
+
+  return p.config.SkipUnchanged && !state.replacing
+}
+
+func (p *Patcher) saveToResults(r *PatchResult, dest reflect.StructField, patch, old, new interface{}) error {
+
+  // Get names for the fields array and the map.
+  fieldName, mapName, err := p.resultNames(dest)
+  if err != nil { return err }
+
+  // Append.
+  r.Fields = append(r.Fields, fieldName)
+
+  // Add to map, and record the change using the same name.
+  r.Map[mapName] = patch
+  r.Changes = append(r.Changes, Change{ Path: mapName, Old: old, New: new })
+
+  return nil
+}
+
+func (p *Patcher) mergeResults(top, deep *PatchResult, dest reflect.StructField, replace bool, old, new interface{}) error {
+
+  // Get names for the fields array and the map.
+  fieldName, mapName, err := p.resultNames(dest)
+  if err != nil { return err }
+
+  // A replaced struct is recorded as a single field and change, while a patched struct's results are mapped to path.
+  if replace {
+    top.Fields = append(top.Fields, fieldName)
+    top.Map[mapName] = deep.Map
+    top.Changes = append(top.Changes, Change{ Path: mapName, Old: old, New: new })
+  } else {
+    appendResults(top, deep, fieldName, mapName)
+  }
+
+  return nil
+}
+
+// appendResults appends the fields, map entries and changes of "deep" to "top", prefixing them with the given paths in dot notation.
+func appendResults(top, deep *PatchResult, fieldPath, mapPath string) {
+
+  for _, field := range(deep.Fields) {
+    top.Fields = append(top.Fields, fieldPath+"."+field)
+  }
+  for k, v := range(deep.Map) {
+    top.Map[mapPath+"."+k] = v
+  }
+  for _, change := range(deep.Changes) {
+    change.Path = mapPath+"."+change.Path
+    top.Changes = append(top.Changes, change)
+  }
+}
+
+// patchName gets the name of "field" in the patch map, based on the configured PatchSource.
+func (p *Patcher) patchName(field reflect.StructField) (string, error) {
+
+  if p.config.PatchSource == "" || p.config.PatchSource == "struct" { return field.Name, nil }
+
+  testFieldName := field.Tag.Get(p.config.PatchSource)
+  if testFieldName != "" { return testFieldName, nil }
+  if p.config.PatchErrors { return "", errFieldMissingTag("", field.Name, p.config.PatchSource) }
+
+  return field.Name, nil
+}
+
+// resultNames gets the names of "dest" in a PatchResult's Fields array and Map, based on the configured UpdatedFieldSource and UpdatedMapSource.
+func (p *Patcher) resultNames(dest reflect.StructField) (string, string, error) {
 
   // Get a field name for the fields array.
   fieldName := dest.Name
@@ -225,59 +597,218 @@ func (p *Patcher) mergeResults(top, deep *PatchResult, dest reflect.StructField,
     if testFieldName != "" {
       fieldName = testFieldName
     } else if p.config.UpdatedFieldErrors {
-      return errFieldMissingTag(fieldName, p.config.UpdatedFieldSource)
-    }
-  }
-
-  // Prepend the embed path if this is root and there is an embed path.
-  if root && p.config.EmbedPath != "" { fieldName = p.config.EmbedPath+"."+fieldName }
-
-  // Map field names to path and append.
-  if replace {
-    top.Fields = append(top.Fields, fieldName)
-  } else {
-    for _, field := range(deep.Fields) {
-      top.Fields = append(top.Fields, fieldName+"."+field)
+      return "", "", errFieldMissingTag("", dest.Name, p.config.UpdatedFieldSource)
     }
   }
 
   // Get a field name for the map.
-  fieldName = dest.Name
+  mapName := dest.Name
   if p.config.UpdatedMapSource != "" && p.config.UpdatedMapSource != "struct" {
     
     testFieldName := dest.Tag.Get(p.config.UpdatedMapSource)
     if testFieldName != "" {
-      fieldName = testFieldName
+      mapName = testFieldName
     } else if p.config.UpdatedMapErrors {
-      return errFieldMissingTag(fieldName, p.config.UpdatedMapSource)
+      return "", "", errFieldMissingTag("", dest.Name, p.config.UpdatedMapSource)
     }
   }
 
-  // Prepend the embed path if this is root.
-  if root && p.config.EmbedPath != "" { fieldName = p.config.EmbedPath+"."+fieldName }
+  return fieldName, mapName, nil
+}
+
+// convert converts "v" into a new value of type "t", using the same rules used to patch a field of that type. Structs and pointers to structs are
+// created from their zero value and deep-patched. If "v" can't be converted, the returned bool is false.
+func (p Patcher) convert(t reflect.Type, v reflect.Value, permitted permissions, state *patchState) (reflect.Value, bool, error) {
+
+  // Values already of the right type need no conversion, while values of the same kind, such as those of named types, are converted if possible.
+  if v.IsValid() && v.Type().AssignableTo(t) { return v, true, nil }
+  if v.IsValid() && v.Kind() == t.Kind() && isScalar(t.Kind()) && v.Type().ConvertibleTo(t) { return v.Convert(t), true, nil }
+
+  // Slices and arrays are converted element by element.
+  if t.Kind() == reflect.Slice || t.Kind() == reflect.Array { return p.convertSlice(t, v, permitted, state) }
+
+  // Check updater functions for a match.
+  out := reflect.New(t).Elem()
+  for _, updater := range Updaters {
+    if updater(out, v) { return out, true, nil }
+  }
+
+  // Deep-patch structs and pointers to structs from map[string]interface{}.
+  structT := t
+  if structT.Kind() == reflect.Ptr { structT = structT.Elem() }
+  if structT.Kind() == reflect.Struct && v.IsValid() {
 
-  if replace {
-    top.Map[fieldName] = deep.Map
-  } else {
-    for k, v := range(deep.Map) {
-      top.Map[fieldName+"."+k] = v
+    m, ok := v.Interface().(map[string]interface{})
+    if !ok { return reflect.Value{}, false, nil }
+
+    elem := reflect.New(structT)
+    if _, err := p.patch(elem.Interface(), m, permitted, state); err != nil { return reflect.Value{}, false, err }
+
+    if t.Kind() == reflect.Ptr { return elem, true, nil }
+    return elem.Elem(), true, nil
+  }
+
+  return reflect.Value{}, false, nil
+}
+
+// converted returns "val" converted to "t" as convert does, and whether it could be, for checking a patch value before the field is patched.
+// Structs patched from a map are converted from their zero value. The conversion only writes to new values, and uses a scratch state, so nothing
+// it does is reported in the results of the patch. As the value is thrown away, the Authorizer, lifecycle hooks, PatchValidators and setters
+// aren't called while converting it.
+func (p Patcher) converted(t reflect.Type, val interface{}, state *patchState) (reflect.Value, bool) {
+
+  scratch := patchState{ ctx: state.ctx, dryRun: true, scratch: true, path: state.path, reasons: map[string]UnpermittedReason{}, roles: state.roles }
+
+  v, ok, err := p.convert(t, reflect.ValueOf(val), permissions{}, &scratch)
+  return v, ok && err == nil && len(scratch.errors) == 0
+}
+
+// equalValues returns whether "a" and "b" are equal, which is how unchanged values are found everywhere. Values of different types are never
+// equal. Pointers and interfaces are compared by the values they hold, and values with an Equal method, such as time.Time and null.Time, are
+// compared with it, so times in different locations are equal if they're the same instant.
+func equalValues(a, b reflect.Value) bool {
+
+  for {
+    if !a.IsValid() || !b.IsValid() { return a.IsValid() == b.IsValid() }
+    if a.Type() != b.Type() { return false }
+    if a.Kind() != reflect.Ptr && a.Kind() != reflect.Interface { break }
+    if a.IsNil() || b.IsNil() { return a.IsNil() == b.IsNil() }
+    a, b = a.Elem(), b.Elem()
+  }
+
+  if equal := a.MethodByName("Equal"); equal.IsValid() {
+    t := equal.Type()
+    if t.NumIn() == 1 && t.In(0) == a.Type() && t.NumOut() == 1 && t.Out(0).Kind() == reflect.Bool {
+      return equal.Call([]reflect.Value{ b })[0].Bool()
     }
   }
 
-  return nil
+  return reflect.DeepEqual(a.Interface(), b.Interface())
 }
 
-func getPermittedInEmbedded(permitted []string, fieldName string) []string {
+// formatValue returns "v" as it's written in the "transitions" tag option: the result of its String method if it has one, such as for enums of
+// named integer types, or its underlying value otherwise. Pointers are formatted by the values they point to, and nil pointers as "".
+func formatValue(v reflect.Value) string {
 
-  if len(permitted) == 0 { return []string{ "*" } }
+  for v.Kind() == reflect.Ptr {
+    if v.IsNil() { return "" }
+    v = v.Elem()
+  }
 
-  out := make([]string, 0, len(permitted))
+  if s, ok := v.Interface().(fmt.Stringer); ok { return s.String() }
+  return fmt.Sprint(v.Interface())
+}
 
-  for _, permitted := range(permitted) {
+// isUnsupported returns whether "kind" is a kind which can't be patched at all.
+func isUnsupported(kind reflect.Kind) bool {
 
-    if permitted == fieldName+".*" { return []string{ "*" } }
+  return kind == reflect.Chan || kind == reflect.Func || kind == reflect.Interface || kind == reflect.UnsafePointer
+}
+
+// isScalar returns whether "kind" is a boolean, numeric or string kind.
+func isScalar(kind reflect.Kind) bool {
+
+  return (kind >= reflect.Bool && kind <= reflect.Complex128) || kind == reflect.String
+}
+
+// isPatchedByPath returns whether "val" patches the fields, elements or keys of "fieldV" individually, rather than replacing its value. Maps are
+// only patched by path if "tag" contains "merge".
+func isPatchedByPath(fieldV reflect.Value, val interface{}, tag fieldTag) bool {
+
+  if _, ok := val.(map[string]interface{}); !ok { return false }
+
+  t := fieldV.Type()
+  if t.Kind() == reflect.Ptr { t = t.Elem() }
+
+  return t.Kind() == reflect.Struct || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || (t.Kind() == reflect.Map && tag.has("merge"))
+}
+
+// expandsField returns a function reporting whether keys in dot notation, split into "head" and "rest" at the first dot, should be expanded into a
+// nested patch for the field of "structV" named "head". Only fields patched by path are expanded: structs not tagged "replace", slices and arrays
+// addressed by index, and maps tagged "merge". Fields with setters are set as a whole, and are never expanded. Keys which aren't expanded are left
+// as they are, so they show up as unknown rather than replacing the field's whole value.
+func (p *Patcher) expandsField(structV reflect.Value) func(head, rest string) bool {
+
+  return func(head, rest string) bool {
+
+    for i := 0; i < structV.NumField(); i++ {
+
+      fieldT := structV.Type().Field(i)
+      if name, err := p.patchName(fieldT); err != nil || name != head { continue }
+      if setter, err := findSetter(structV, fieldT); err != nil || setter != nil { return false }
+
+      tag := parseTag(fieldT)
+      t := fieldT.Type
+      if t.Kind() == reflect.Ptr { t = t.Elem() }
+
+      switch t.Kind() {
+      case reflect.Struct:
+        return !tag.has("replace")
+      case reflect.Slice, reflect.Array:
+        if _, hasKey := tag.option("key"); hasKey && tag.has("merge") { return false }
+        index := rest
+        if i := strings.Index(rest, "."); i >= 0 { index = rest[:i] }
+        _, err := strconv.Atoi(index)
+        return err == nil
+      case reflect.Map:
+        return tag.has("merge")
+      }
+      return false
+    }
+
+    return false
+  }
+}
 
-    if strings.HasPrefix(permitted, fieldName+".") { out = append(out, permitted[len(fieldName)+1:])}
+// expandsElem returns a function reporting whether keys in dot notation should be expanded into nested patches for the elements of "t", a map,
+// slice or array, which is only the case if they're structs or pointers to structs.
+func expandsElem(t reflect.Type) func(head, rest string) bool {
+
+  elemT := t.Elem()
+  if elemT.Kind() == reflect.Ptr { elemT = elemT.Elem() }
+
+  return func(head, rest string) bool { return elemT.Kind() == reflect.Struct }
+}
+
+// expandPaths expands keys in dot notation, such as "addresses.1.city", into nested patches, such as `"addresses": {"1.city": ...}`, merging them
+// with any nested patch already present. Only keys for which "expand" returns true are expanded, while the rest are left as they are. The keys of
+// each nested patch are expanded in turn as it is patched. The original patch isn't modified.
+func expandPaths(patch map[string]interface{}, expand func(head, rest string) bool) map[string]interface{} {
+
+  // Most patches have no keys in dot notation to expand, and are returned as-is.
+  expanded := make(map[string]bool)
+  for k := range(patch) {
+    if i := strings.Index(k, "."); i >= 0 && expand(k[:i], k[i+1:]) { expanded[k] = true }
+  }
+  if len(expanded) == 0 { return patch }
+
+  // Copy keys which aren't expanded first, copying nested patches so they can be added to.
+  out := make(map[string]interface{}, len(patch))
+  for k, v := range(patch) {
+    if expanded[k] { continue }
+
+    if m, ok := v.(map[string]interface{}); ok {
+      nested := make(map[string]interface{}, len(m))
+      for nk, nv := range(m) { nested[nk] = nv }
+      v = nested
+    }
+    out[k] = v
+  }
+
+  // Move each key in dot notation into its nested patch, leaving it as-is if its first segment isn't a nested patch.
+  for k, v := range(patch) {
+    if !expanded[k] { continue }
+
+    i := strings.Index(k, ".")
+    head, rest := k[:i], k[i+1:]
+    if _, exists := out[head]; !exists { out[head] = map[string]interface{}{} }
+
+    nested, ok := out[head].(map[string]interface{})
+    if !ok {
+      out[k] = v
+      continue
+    }
+    nested[rest] = v
   }
 
   return out
@@ -38,9 +38,8 @@ type PatcherConfig struct {
   // PatchSource, and PatchSource is not empty or "struct". Defaults to
   // false.
   //
-  // WARNING: Using this option may result in half-patched structures!
-  // Only use this if you don't have further use of the half-patched
-  // structure or can reload it afterwards.
+  // Any changes already made to the structure are undone before the
+  // error is returned.
   PatchErrors bool
 
   // UpdatedMapSource, defaulting to "struct" when empty, determines from
@@ -59,9 +58,8 @@ type PatcherConfig struct {
   // UpdatedMapSource, and UpdatedMapSource is not empty or "struct".
   // Defaults to false.
   //
-  // WARNING: Using this option may result in half-patched structures!
-  // Only use this if you don't have further use of the half-patched
-  // structure or can reload it afterwards.
+  // Any changes already made to the structure are undone before the
+  // error is returned.
   UpdatedMapErrors bool
 
   // UpdatedFieldSource, defaulting to "struct" when empty, determines
@@ -80,9 +78,8 @@ type PatcherConfig struct {
   // UpdatedFieldSource, and UpdatedFieldSource is not empty or "struct".
   // Defaults to false.
   //
-  // WARNING: Using this option may result in half-patched structures!
-  // Only use this if you don't have further use of the half-patched
-  // structure or can reload it afterwards.
+  // Any changes already made to the structure are undone before the
+  // error is returned.
   UpdatedFieldErrors bool
 
   // PermittedFields, if set, will prevent patches from fields in the
@@ -99,17 +96,75 @@ type PatcherConfig struct {
   // // updates.Fields == []string{"email_address"}
   // // updates.Unpermitted == []string{"password_hash"}
   //
-  // To permit all fields of an embedded struct, use `embedded.*`. All
-  // fields found to be unpermitted will be stored in dot notation in
-  // the PatchResult's UnpermittedFields array if UnpermittedErrors is
-  // false.
+  // Each entry is a pattern matched against a field's path in dot
+  // notation, using names from the PatchSource, and permits only the
+  // fields it matches, while a pattern ending in `*` or `**` permits
+  // everything inside them too. For example, `profile` permits the
+  // profile field as a whole but none of the fields inside it, while
+  // `profile.*` permits every field inside it. A segment can be a
+  // glob such as `addr*`, and a `*` segment stands in for any single
+  // field name, map key or slice index, such as `addresses.*.city`. A
+  // `**` segment stands in for any number of them, such as
+  // `**.city`. To permit all fields of an embedded struct, use
+  // `embedded.*`. Patterns starting with `!` exclude the fields they
+  // match, such as `!profile.internal_notes`, and take priority over
+  // any other pattern. An array of only exclusions permits everything
+  // else. Patterns are compiled by New, and if any of them are invalid,
+  // every patch returns the error. NewPatcher returns it instead.
+  //
+  // All fields found to be unpermitted, at any level of the structure,
+  // will be stored with their absolute path in dot notation in the
+  // PatchResult's Unpermitted array if UnpermittedErrors is false.
   PermittedFields []string
 
+  // DenyByDefault causes an empty or nil PermittedFields array, or one
+  // holding only exclusions, to permit nothing rather than everything.
+  // Fields must then be granted by a pattern, or tagged with the
+  // "gopatch" tag "public", to be patched. This prevents a mistake which
+  // empties PermittedFields from making every field patchable. Defaults
+  // to false.
+  DenyByDefault bool
+
+  // Authorizer, if set, is asked whether each field may be patched once
+  // it has passed every other permission check, allowing decisions based
+  // on the context passed to PatchContext. Fields it doesn't allow are
+  // unpermitted. See Authorizer.
+  Authorizer Authorizer
+
   // UnpermittedErrors causes the Patcher to immediately return an error
   // if a field is found to be unpermitted.
   //
-  // WARNING: Using this option may result in half-patched structures!
-  // Only use this if you don't have further use of the half-patched
-  // structure or can reload it afterwards.
+  // Any changes already made to the structure are undone before the
+  // error is returned.
   UnpermittedErrors bool
+
+  // SkipUnchanged causes the Patcher to leave out fields whose patched
+  // value, after any conversion made by Updaters, is equal to the value
+  // they already hold. These fields are not written to, and are left out
+  // of the PatchResult. Embedded structs with no changed fields are left
+  // out entirely, while embedded structs with the "gopatch" tag set to
+  // "replace" are compared as a whole. Values are compared as immutable
+  // fields are, so times at the same instant in different locations are
+  // equal. Defaults to false.
+  SkipUnchanged bool
+
+  // CollectErrors causes the Patcher to go through the whole patch, even
+  // after a field fails to patch, and return an ErrorList holding every
+  // PatchError found, such as unpermitted fields, missing tags and failed
+  // conversions. Any changes made to the structure are still undone
+  // before the error is returned. Defaults to false.
+  CollectErrors bool
+
+  // RejectUnknownFields causes the Patcher to return an error if a key
+  // in the patch matches no field, rather than listing it in the
+  // PatchResult's Unknown array. This is similar to the
+  // DisallowUnknownFields option of encoding/json's Decoder. Defaults to
+  // false.
+  RejectUnknownFields bool
+
+  // StrictTypes causes the Patcher to return an error if a field's patch
+  // value can't be patched into it, such as a string patched into a bool
+  // field, rather than listing it in the PatchResult's Skipped array.
+  // Defaults to false.
+  StrictTypes bool
 }
\ No newline at end of file
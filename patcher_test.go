@@ -1,11 +1,102 @@
 package gopatch
 
 import(
+  "context"
+  "errors"
   "reflect"
   "strings"
   "testing"
+  "time"
+
+  "github.com/guregu/null"
+)
+
+// testPeriod is a PatchValidator requiring its start to precede its end.
+type testPeriod struct {
+  Start  int
+  End    int
+}
+
+func (p *testPeriod) ValidatePatch(results *PatchResult) error {
+
+  if p.Start >= p.End { return errors.New("start must precede end") }
+  return nil
+}
+
+// testArticle is a BeforePatcher trimming its title and rejecting patches to locked articles, and an AfterPatcher deriving its slug.
+type testArticle struct {
+  Title   string
+  Slug    string
+  Locked  bool
+}
+
+func (a *testArticle) BeforePatch(patch map[string]interface{}) error {
+
+  if a.Locked { return errors.New("article is locked") }
+  if title, ok := patch["Title"].(string); ok { patch["Title"] = strings.TrimSpace(title) }
+  return nil
+}
+
+func (a *testArticle) AfterPatch(results *PatchResult) error {
+
+  old := a.Slug
+  a.Slug = strings.ReplaceAll(strings.ToLower(a.Title), " ", "-")
+
+  results.Fields = append(results.Fields, "Slug")
+  results.Map["Slug"] = a.Slug
+  results.Changes = append(results.Changes, Change{ Path: "Slug", Old: old, New: a.Slug })
+  return nil
+}
+
+// testMember has setters for an unexported field, found by convention, and for an exported field, named by the gopatch tag.
+type testMember struct {
+  Name   string  `gopatch:"setter=Rename"`
+  email  string
+}
+
+func (m *testMember) Rename(name string) error {
+
+  m.Name = strings.ToUpper(name[:1])+name[1:]
+  return nil
+}
+
+func (m *testMember) SetEmail(email string) error {
+
+  if !strings.Contains(email, "@") { return errors.New("invalid email") }
+  m.email = email
+  return nil
+}
+
+// testAuthor has a setter for a struct field, which is set as a whole.
+type testAuthor struct {
+  Prof  testProfile
+}
+
+type testProfile struct {
+  Bio   string
+  Site  string
+}
+
+func (a *testAuthor) SetProf(prof testProfile) error {
+
+  a.Prof = prof
+  return nil
+}
+
+// testState is an enum with names, used in "transitions" tag options.
+type testState int
+
+const (
+  testDraft testState = iota
+  testReview
+  testPublished
 )
 
+func (s testState) String() string {
+
+  return [...]string{ "draft", "review", "published" }[s]
+}
+
 func TestPatcher(t *testing.T) {
 
   type TestDouble struct {
@@ -490,4 +581,1456 @@ func TestPatcher(t *testing.T) {
       return
     }
   })
+  t.Run("error-rolls-back", func(t *testing.T) {
+
+    type TestPointer struct {
+      Field1  string
+      Field2  *TestEmbedded
+      Field3  int
+    }
+
+    cfg := PatcherConfig{
+      PermittedFields: []string{"Field1", "Field2.*"},
+      UnpermittedErrors: true,
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestPointer{ Field1: "original" }
+
+    _, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field2": map[string]interface{}{
+        "Field2": 255,
+      },
+      "Field3": 255,
+    })
+
+    // Test for expected errors.
+    if err == nil {
+      t.Errorf("Expected patch error, but didn't get one.")
+      return
+    }
+
+    // Test to see if the instance was left untouched, including the nil pointer.
+    if !reflect.DeepEqual(testInstance, TestPointer{ Field1: "original" }) {
+      t.Errorf("Expected failed patch to leave struct untouched. Patch affected struct so: %v", testInstance)
+      return
+    }
+  })
+  t.Run("preview", func(t *testing.T) {
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{ Field1: "original" }
+
+    result, err := patcher.Preview(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field5": map[string]interface{}{
+        "Field2": 255,
+      },
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the instance was left untouched.
+    if !reflect.DeepEqual(testInstance, TestStruct{ Field1: "original" }) {
+      t.Errorf("Expected preview to leave struct untouched. Patch affected struct so: %v", testInstance)
+      return
+    }
+
+    // Test to see if the resulting update map is correct.
+    if v, e := result.Map["Field5.Field2"]; !e || v != 255 || result.Map["Field1"] != "test" || len(result.Map) != 2 {
+      t.Errorf("Expected patch result map to contain exactly \"Field1\": \"test\" and \"Field5.Field2\": 255. Contained %v", result.Map)
+      return
+    }
+    type TestPreview struct {
+      Field1  *TestDouble
+      Field2  *testMember
+    }
+
+    // Test to see if nothing the struct points to is written, and setters aren't called.
+    pointed := TestPreview{ Field1: &TestDouble{ Field1: "original" }, Field2: &testMember{ Name: "original" } }
+    result, err = patcher.Preview(&pointed, map[string]interface{}{
+      "Field1": map[string]interface{}{ "Field1": "test" },
+      "Field2": map[string]interface{}{ "Name": "test" },
+    })
+    if err != nil || pointed.Field1.Field1 != "original" || pointed.Field2.Name != "original" {
+      t.Errorf("Expected preview to leave pointed structs untouched. Patch affected them so: %v, %v, with error: %v", *pointed.Field1, *pointed.Field2, err)
+      return
+    }
+    if result.Map["Field1.Field1"] != "test" || result.Map["Field2.Name"] != "test" {
+      t.Errorf("Expected patch result map to contain the values without calling setters. Contained %v", result.Map)
+      return
+    }
+    type TestAliased struct {
+      Field1  *TestEmbedded
+      Field2  *string
+    }
+
+    // Test to see if pointers of different types sharing an address are copied separately.
+    aliased := TestAliased{ Field1: &TestEmbedded{ Field1: "original" } }
+    aliased.Field2 = &aliased.Field1.Field1
+    result, err = patcher.Preview(&aliased, map[string]interface{}{
+      "Field1": map[string]interface{}{ "Field1": "test" },
+    })
+    if err != nil || aliased.Field1.Field1 != "original" || result.Map["Field1.Field1"] != "test" {
+      t.Errorf("Expected preview to leave aliased pointers untouched. Patch affected struct so: %v, with error: %v", *aliased.Field1, err)
+      return
+    }
+  })
+  t.Run("changes", func(t *testing.T) {
+
+    cfg := PatcherConfig{
+      UpdatedMapSource: "bson",
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{
+      Field1: "original",
+      Field5: TestEmbedded{
+        Field2: 1,
+      },
+    }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field5": map[string]interface{}{
+        "Field2": 255.0,
+      },
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if both changes were recorded with their old and converted new values.
+    expected := []Change{
+      { Path: "field1", Old: "original", New: "test" },
+      { Path: "Field5.Field2", Old: 1, New: 255 },
+    }
+    if !reflect.DeepEqual(result.Changes, expected) {
+      t.Errorf("Expected patch result changes to contain exactly %v. Contained %v", expected, result.Changes)
+      return
+    }
+  })
+  t.Run("skip-unchanged", func(t *testing.T) {
+
+    cfg := PatcherConfig{
+      SkipUnchanged: true,
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{
+      Field1: "test",
+      Field2: 255,
+      Field4: TestEmbedded{
+        Field2: 1,
+      },
+      Field5: TestEmbedded{
+        Field2: 1,
+      },
+    }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field2": 255.0,
+      "Field4": map[string]interface{}{
+        "Field2": 1,
+      },
+      "Field5": map[string]interface{}{
+        "Field1": "test",
+        "Field2": 1,
+      },
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the instance was patched.
+    if testInstance.Field5.Field1 != "test" {
+      t.Errorf("Expected patch to patch Field5.Field1. Patch affected struct so: %v", testInstance)
+      return
+    }
+
+    // Test to see if the instance field list only contains the changed field.
+    if len(result.Fields) != 1 || result.Fields[0] != "Field5.Field1" {
+      t.Errorf("Expected patch result fields to contain exactly \"Field5.Field1\". Contained [%v]", strings.Join(result.Fields, ", "))
+      return
+    }
+
+    // Test to see if the resulting update map only contains the changed field.
+    if v, e := result.Map["Field5.Field1"]; !e || v != "test" || len(result.Map) > 1 {
+      t.Errorf("Expected patch result map to contain exactly \"Field5.Field1\": \"test\". Contained %v", result.Map)
+      return
+    }
+
+    type TestTimes struct {
+      Field1  time.Time
+      Field2  []time.Time
+      Field3  map[string]time.Time  `gopatch:"merge"`
+    }
+
+    // Test to see if values converted by Updaters are unchanged if they're the same instant in another location.
+    instant := time.Date(2020, 1, 1, 0, 0, 0, 0, time.FixedZone("X", 0))
+    times := TestTimes{ Field1: instant, Field2: []time.Time{ instant }, Field3: map[string]time.Time{ "a": instant } }
+    result, err = patcher.Patch(&times, map[string]interface{}{
+      "Field1": "2020-01-01T00:00:00Z",
+      "Field2": map[string]interface{}{ "0": "2020-01-01T00:00:00Z" },
+      "Field3": map[string]interface{}{ "a": "2020-01-01T00:00:00Z" },
+    })
+    if err != nil || len(result.Fields) != 0 || len(result.Map) != 0 || len(result.Changes) != 0 {
+      t.Errorf("Expected patch to leave out times at the same instant. Fields [%v], with error: %v", strings.Join(result.Fields, ", "), err)
+      return
+    }
+  })
+  t.Run("slices", func(t *testing.T) {
+
+    type TestSlices struct {
+      Field1  []string
+      Field2  [3]int
+      Field3  []TestDouble
+    }
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestSlices{
+      Field1: []string{"original"},
+    }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": []interface{}{"test1", "test2"},
+      "Field2": []interface{}{1.0, 2.0},
+      "Field3": []interface{}{
+        map[string]interface{}{ "Field1": "test", "Field2": 255.0 },
+      },
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the instance was patched.
+    expected := TestSlices{
+      Field1: []string{"test1", "test2"},
+      Field2: [3]int{1, 2, 0},
+      Field3: []TestDouble{{ Field1: "test", Field2: 255 }},
+    }
+    if !reflect.DeepEqual(testInstance, expected) {
+      t.Errorf("Expected patch to replace all slices. Patch affected struct so: %v", testInstance)
+      return
+    }
+
+    // Test to see if the resulting update map contains the converted slices.
+    if v, e := result.Map["Field1"]; !e || !reflect.DeepEqual(v, expected.Field1) || len(result.Map) != 3 {
+      t.Errorf("Expected patch result map to contain exactly the converted slices. Contained %v", result.Map)
+      return
+    }
+    // Test to see if a slice permitted by an exact pattern is replaced with every field of its new elements.
+    testInstance = TestSlices{}
+    result, err = New(PatcherConfig{ PermittedFields: []string{ "Field3" } }).Patch(&testInstance, map[string]interface{}{
+      "Field3": []interface{}{
+        map[string]interface{}{ "Field1": "test", "Field2": 255.0 },
+      },
+    })
+    if err != nil || !reflect.DeepEqual(testInstance.Field3, expected.Field3) || len(result.Unpermitted) != 0 {
+      t.Errorf("Expected patch to replace Field3 whole. Patch affected struct so: %v, unpermitting [%v], with error: %v", testInstance, strings.Join(result.Unpermitted, ", "), err)
+      return
+    }
+  })
+  t.Run("slices-merge", func(t *testing.T) {
+
+    type TestItem struct {
+      ID      int     `json:"id"`
+      Qty     int     `json:"qty"`
+      Secret  string  `json:"secret"`
+    }
+
+    type TestMerge struct {
+      Items  []TestItem  `json:"items"  gopatch:"merge,key=id"`
+    }
+
+    cfg := PatcherConfig{
+      PatchSource: "json",
+      UpdatedMapSource: "json",
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestMerge{
+      Items: []TestItem{
+        { ID: 41, Qty: 1, Secret: "a" },
+        { ID: 42, Qty: 1, Secret: "b" },
+      },
+    }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "items": []interface{}{
+        map[string]interface{}{ "id": 42.0, "qty": 5.0 },
+        map[string]interface{}{ "id": 41.0, MergeDeleteKey: true },
+        map[string]interface{}{ "id": 43.0, "qty": 1.0 },
+      },
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the instance was patched, keeping server-only fields.
+    expected := []TestItem{
+      { ID: 42, Qty: 5, Secret: "b" },
+      { ID: 43, Qty: 1 },
+    }
+    if !reflect.DeepEqual(testInstance.Items, expected) {
+      t.Errorf("Expected patch to merge items into %v. Patch affected struct so: %v", expected, testInstance)
+      return
+    }
+
+    // Test to see if the resulting update map uses keyed paths.
+    if v, e := result.Map["items[id=42].qty"]; !e || v != 5.0 || len(result.Map) != 3 {
+      t.Errorf("Expected patch result map to contain \"items[id=42].qty\": 5, \"items[id=41]\" and \"items[id=43]\". Contained %v", result.Map)
+      return
+    }
+    if v, e := result.Map["items[id=41]"]; !e || v != nil {
+      t.Errorf("Expected patch result map to contain \"items[id=41]\": nil. Contained %v", result.Map)
+      return
+    }
+    // Test to see if elements without a key are skipped by their index in the patch, rather than appended.
+    result, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "items": []interface{}{
+        map[string]interface{}{ "qty": 2.0 },
+        map[string]interface{}{ "id": nil, "qty": 3.0 },
+      },
+    })
+    skipped := []Skip{ { Path: "items.0", Reason: SkipMissingKey }, { Path: "items.1", Reason: SkipMissingKey } }
+    if err != nil || !reflect.DeepEqual(testInstance.Items, expected) || !reflect.DeepEqual(result.Skipped, skipped) || len(result.Map) != 0 {
+      t.Errorf("Expected patch to skip elements without a key as %v. Skipped %v, map contained %v, with error: %v", skipped, result.Skipped, result.Map, err)
+      return
+    }
+  })
+  t.Run("slices-indexes", func(t *testing.T) {
+
+    type TestAddress struct {
+      City    string  `json:"city"`
+      Street  string  `json:"street"`
+    }
+
+    type TestIndexes struct {
+      Addresses  []TestAddress  `json:"addresses"`
+    }
+
+    cfg := PatcherConfig{
+      PatchSource: "json",
+      UpdatedMapSource: "json",
+      PermittedFields: []string{"addresses.*.city"},
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestIndexes{
+      Addresses: []TestAddress{
+        { City: "Bergen", Street: "a" },
+        { City: "Bergen", Street: "b" },
+      },
+    }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "addresses": map[string]interface{}{
+        "1": map[string]interface{}{ "city": "Oslo", "street": "c" },
+      },
+      "addresses.0.city": "Trondheim",
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if only the permitted fields of the addressed elements were patched.
+    expected := []TestAddress{
+      { City: "Trondheim", Street: "a" },
+      { City: "Oslo", Street: "b" },
+    }
+    if !reflect.DeepEqual(testInstance.Addresses, expected) {
+      t.Errorf("Expected patch to patch addresses into %v. Patch affected struct so: %v", expected, testInstance)
+      return
+    }
+
+    // Test to see if the resulting update map uses indexed paths.
+    if v, e := result.Map["addresses.1.city"]; !e || v != "Oslo" || result.Map["addresses.0.city"] != "Trondheim" || len(result.Map) != 2 {
+      t.Errorf("Expected patch result map to contain exactly \"addresses.0.city\" and \"addresses.1.city\". Contained %v", result.Map)
+      return
+    }
+
+    // Test to see if out of range indexes error.
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "addresses.2.city": "Oslo",
+    })
+    if err == nil {
+      t.Errorf("Expected patch error, but didn't get one.")
+      return
+    }
+  })
+  t.Run("maps-merge", func(t *testing.T) {
+
+    type TestMaps struct {
+      Settings  map[string]string       `json:"settings"  gopatch:"merge"`
+      Doubles   map[string]TestDouble   `json:"doubles"   gopatch:"merge"`
+    }
+
+    cfg := PatcherConfig{
+      PatchSource: "json",
+      UpdatedMapSource: "json",
+      PermittedFields: []string{"settings.theme", "settings.locale", "doubles.*"},
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestMaps{
+      Settings: map[string]string{ "theme": "light", "locale": "en", "admin": "no" },
+      Doubles: map[string]TestDouble{ "a": { Field1: "test", Field2: 1 } },
+    }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "settings": map[string]interface{}{
+        "theme": "dark",
+        "locale": nil,
+        "admin": "yes",
+      },
+      "doubles.a.Field2": 255.0,
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the maps were merged.
+    expected := TestMaps{
+      Settings: map[string]string{ "theme": "dark", "admin": "no" },
+      Doubles: map[string]TestDouble{ "a": { Field1: "test", Field2: 255 } },
+    }
+    if !reflect.DeepEqual(testInstance, expected) {
+      t.Errorf("Expected patch to merge maps into %v. Patch affected struct so: %v", expected, testInstance)
+      return
+    }
+
+    // Test to see if the unpermitted key was recorded.
+    if len(result.Unpermitted) != 1 || result.Unpermitted[0] != "settings.admin" {
+      t.Errorf("Expected patch result unpermitted to contain exactly \"settings.admin\". Contained [%v]", strings.Join(result.Unpermitted, ", "))
+      return
+    }
+
+    // Test to see if the resulting update map uses per-key paths.
+    if v, e := result.Map["settings.locale"]; !e || v != nil || result.Map["settings.theme"] != "dark" || result.Map["doubles.a.Field2"] != 255.0 || len(result.Map) != 3 {
+      t.Errorf("Expected patch result map to contain exactly \"settings.theme\", \"settings.locale\" and \"doubles.a.Field2\". Contained %v", result.Map)
+      return
+    }
+  })
+  t.Run("maps-unmerged-paths", func(t *testing.T) {
+
+    type TestMaps struct {
+      Settings  map[string]interface{}  `json:"settings"`
+      Doubles   map[string]TestDouble   `json:"doubles"   gopatch:"replace"`
+    }
+
+    cfg := PatcherConfig{
+      PatchSource: "json",
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestMaps{
+      Settings: map[string]interface{}{ "theme": "light", "lang": "en" },
+      Doubles: map[string]TestDouble{ "a": { Field1: "test", Field2: 1 } },
+    }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "settings.theme": "dark",
+      "doubles.a.Field2": 255.0,
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the maps weren't replaced by the keys in dot notation.
+    expected := TestMaps{
+      Settings: map[string]interface{}{ "theme": "light", "lang": "en" },
+      Doubles: map[string]TestDouble{ "a": { Field1: "test", Field2: 1 } },
+    }
+    if !reflect.DeepEqual(testInstance, expected) {
+      t.Errorf("Expected patch to leave maps unchanged as %v. Patch affected struct so: %v", expected, testInstance)
+      return
+    }
+
+    // Test to see if the keys in dot notation were recorded as unknown.
+    if !reflect.DeepEqual(result.Unknown, []string{"doubles.a.Field2", "settings.theme"}) {
+      t.Errorf("Expected patch result unknown to contain exactly \"doubles.a.Field2\" and \"settings.theme\". Contained [%v]", strings.Join(result.Unknown, ", "))
+      return
+    }
+  })
+  t.Run("named-types", func(t *testing.T) {
+
+    type TestStatus string
+    type TestCents int64
+    type TestFlag bool
+
+    type TestNamed struct {
+      Field1  TestStatus
+      Field2  TestCents
+      Field3  TestFlag
+      Field4  []TestStatus
+      Field5  *TestDouble
+    }
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestNamed{}
+
+    _, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "active",
+      "Field2": 255.0,
+      "Field3": true,
+      "Field4": []interface{}{"active", "banned"},
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the instance was patched with converted values.
+    expected := TestNamed{
+      Field1: "active",
+      Field2: 255,
+      Field3: true,
+      Field4: []TestStatus{"active", "banned"},
+    }
+    if !reflect.DeepEqual(testInstance, expected) {
+      t.Errorf("Expected patch to convert all named types. Patch affected struct so: %v", testInstance)
+      return
+    }
+
+    // Test to see if an impossible conversion errors instead of panicking.
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "Field5": &TestEmbedded{},
+    })
+    if err == nil {
+      t.Errorf("Expected patch error, but didn't get one.")
+      return
+    }
+  })
+  t.Run("panic-recovered", func(t *testing.T) {
+
+    // Temporarily add an updater which panics when patching strings into ints.
+    defaultUpdaters := Updaters
+    defer func() { Updaters = defaultUpdaters }()
+    Updaters = append([]func(reflect.Value, reflect.Value) bool{
+      func(fieldValue reflect.Value, v reflect.Value) bool {
+        if fieldValue.Kind() == reflect.Int && v.Kind() == reflect.String { panic("test") }
+        return false
+      },
+    }, defaultUpdaters...)
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{}
+
+    _, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field4": map[string]interface{}{
+        "Field2": "test",
+      },
+    })
+
+    // Test for the expected panic error, naming the field's path and the value's type.
+    var panicErr *PanicError
+    if !errors.As(err, &panicErr) || !errors.Is(err, ErrTypeMismatch) {
+      t.Errorf("Expected patch panic error, but got: %v", err)
+      return
+    }
+    if panicErr.Path != "Field4.Field2" || panicErr.ValueType != reflect.TypeOf("") {
+      t.Errorf("Expected patch panic error for \"Field4.Field2\" with a string value. Got: %v", panicErr)
+      return
+    }
+
+    // Test to see if the instance was left untouched.
+    if !reflect.DeepEqual(testInstance, TestStruct{}) {
+      t.Errorf("Expected failed patch to leave struct untouched. Patch affected struct so: %v", testInstance)
+      return
+    }
+  })
+  t.Run("patch-error", func(t *testing.T) {
+
+    cfg := PatcherConfig{
+      PermittedFields: []string{"Field4.Field1"},
+      UnpermittedErrors: true,
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{}
+
+    _, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field4": map[string]interface{}{
+        "Field1": "test",
+        "Field2": 255,
+      },
+    })
+
+    // Test for the expected patch error, naming the field's full path.
+    var patchErr *PatchError
+    if !errors.As(err, &patchErr) || !errors.Is(err, ErrUnpermitted) {
+      t.Errorf("Expected unpermitted patch error, but got: %v", err)
+      return
+    }
+    if patchErr.Path != "Field4.Field2" || patchErr.Field != "Field2" || patchErr.Code != CodeUnpermitted {
+      t.Errorf("Expected unpermitted patch error for \"Field4.Field2\". Got: %#v", patchErr)
+      return
+    }
+  })
+  t.Run("collect-errors", func(t *testing.T) {
+
+    cfg := PatcherConfig{
+      PermittedFields: []string{"Field1", "Field4.Field1"},
+      UnpermittedErrors: true,
+      CollectErrors: true,
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{}
+
+    _, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field2": 255,
+      "Field3": true,
+      "Field4": map[string]interface{}{
+        "Field1": "test",
+        "Field2": 255,
+      },
+    })
+
+    // Test for the expected list of every field error.
+    errs, ok := err.(ErrorList)
+    if !ok || len(errs) != 3 || !errors.Is(err, ErrUnpermitted) {
+      t.Errorf("Expected a list of 3 unpermitted patch errors, but got: %v", err)
+      return
+    }
+    paths := []string{ errs[0].Path, errs[1].Path, errs[2].Path }
+    if !reflect.DeepEqual(paths, []string{"Field2", "Field3", "Field4.Field2"}) {
+      t.Errorf("Expected errors for \"Field2\", \"Field3\" and \"Field4.Field2\". Got: %v", err)
+      return
+    }
+
+    // Test to see if the instance was left untouched.
+    if !reflect.DeepEqual(testInstance, TestStruct{}) {
+      t.Errorf("Expected failed patch to leave struct untouched. Patch affected struct so: %v", testInstance)
+      return
+    }
+  })
+  t.Run("unknown-fields", func(t *testing.T) {
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{}
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Feild2": 255,
+      "Field4": map[string]interface{}{
+        "Field9": true,
+      },
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the unknown keys were recorded with absolute paths.
+    if !reflect.DeepEqual(result.Unknown, []string{"Feild2", "Field4.Field9"}) {
+      t.Errorf("Expected patch result unknown to contain exactly \"Feild2\" and \"Field4.Field9\". Contained [%v]", strings.Join(result.Unknown, ", "))
+      return
+    }
+
+    // Test to see if unknown keys are prepended with the embed path.
+    result, err = New(PatcherConfig{ EmbedPath: "test" }).Patch(&testInstance, map[string]interface{}{
+      "Field4": map[string]interface{}{ "Field9": true },
+    })
+    if err != nil || !reflect.DeepEqual(result.Unknown, []string{"test.Field4.Field9"}) {
+      t.Errorf("Expected patch result unknown to contain exactly \"test.Field4.Field9\". Contained [%v], with error: %v", strings.Join(result.Unknown, ", "), err)
+      return
+    }
+
+    // Test to see if unknown keys error when rejected.
+    patcher = New(PatcherConfig{ RejectUnknownFields: true })
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "Feild2": 255,
+    })
+    if !errors.Is(err, ErrUnknownField) {
+      t.Errorf("Expected unknown field patch error, but got: %v", err)
+      return
+    }
+  })
+
+  t.Run("skipped-fields", func(t *testing.T) {
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{}
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field2": "255",
+      "Field4": 255,
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the mismatched fields were reported with reasons.
+    expected := []Skip{
+      { Path: "Field2", Reason: SkipIncompatibleType },
+      { Path: "Field4", Reason: SkipIncompatibleType },
+    }
+    if !reflect.DeepEqual(result.Skipped, expected) {
+      t.Errorf("Expected patch result skipped to be %v. Was %v", expected, result.Skipped)
+      return
+    }
+
+    // Test to see if the valid field was still patched.
+    if testInstance.Field1 != "test" {
+      t.Errorf("Expected Field1 to be \"test\". Was %q", testInstance.Field1)
+      return
+    }
+
+    // Test to see if skipped fields are prepended with the embed path.
+    result, err = New(PatcherConfig{ EmbedPath: "test" }).Patch(&testInstance, map[string]interface{}{
+      "Field4": map[string]interface{}{ "Field2": "255" },
+    })
+    expected = []Skip{ { Path: "test.Field4.Field2", Reason: SkipIncompatibleType } }
+    if err != nil || !reflect.DeepEqual(result.Skipped, expected) {
+      t.Errorf("Expected patch result skipped to be %v. Was %v, with error: %v", expected, result.Skipped, err)
+      return
+    }
+
+    // Test to see if mismatched fields error with strict types.
+    patcher = New(PatcherConfig{ StrictTypes: true })
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "Field2": "255",
+    })
+    var patchErr *PatchError
+    if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &patchErr) || patchErr.Path != "Field2" {
+      t.Errorf("Expected type mismatch patch error at \"Field2\", but got: %v", err)
+      return
+    }
+  })
+
+  t.Run("unpermitted-nested", func(t *testing.T) {
+
+    cfg := PatcherConfig{
+      EmbedPath: "test",
+      PermittedFields: []string{ "Field1", "Field4.Field1", "Field4.Field3" },
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{}
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field4": map[string]interface{}{
+        "Field1": "test",
+        "Field2": 255,
+      },
+      "Field3": true,
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if nested unpermitted fields were kept with absolute paths.
+    if !reflect.DeepEqual(result.Unpermitted, []string{"test.Field3", "test.Field4.Field2"}) {
+      t.Errorf("Expected patch result unpermitted to contain exactly \"test.Field3\" and \"test.Field4.Field2\". Contained [%v]", strings.Join(result.Unpermitted, ", "))
+      return
+    }
+
+    // Test to see if each unpermitted field has the reason it was blocked.
+    expected := map[string]UnpermittedReason{
+      "test.Field3": UnpermittedByTag,
+      "test.Field4.Field2": UnpermittedByArray,
+    }
+    if !reflect.DeepEqual(result.UnpermittedReasons, expected) {
+      t.Errorf("Expected patch result unpermitted reasons to be %v. Were %v", expected, result.UnpermittedReasons)
+      return
+    }
+  })
+
+  t.Run("permitted-patterns", func(t *testing.T) {
+
+    cfg := PatcherConfig{
+      PermittedFields: []string{ "**.Field1", "Field4.*", "!Field4.Field4.Field2", "Field5.Field[23]" },
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{}
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field2": 255,
+      "Field4": map[string]interface{}{
+        "Field2": 255,
+        "Field4": map[string]interface{}{ "Field1": "test", "Field2": 255 },
+      },
+      "Field5": map[string]interface{}{ "Field1": "test", "Field2": 255, "Field3": true },
+      "Field6": map[string]interface{}{ "Field1": "test" },
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the instance was patched according to the patterns.
+    expected := TestStruct{
+      Field1: "test",
+      Field4: TestEmbedded{ Field2: 255, Field4: TestDouble{ Field1: "test" } },
+      Field5: TestEmbedded{ Field1: "test", Field2: 255, Field3: true },
+    }
+    if !reflect.DeepEqual(testInstance, expected) {
+      t.Errorf("Expected patch to patch the struct so: %v. Patch affected struct so: %v", expected, testInstance)
+      return
+    }
+
+    // Test to see if the unpermitted fields, including the excluded one, were recorded.
+    if !reflect.DeepEqual(result.Unpermitted, []string{"Field2", "Field4.Field4.Field2", "Field6"}) {
+      t.Errorf("Expected patch result unpermitted to contain exactly \"Field2\", \"Field4.Field4.Field2\" and \"Field6\". Contained [%v]", strings.Join(result.Unpermitted, ", "))
+      return
+    }
+
+    // Test to see if a pattern matching a struct exactly permits none of the fields inside it.
+    testInstance = TestStruct{}
+    result, err = New(PatcherConfig{ PermittedFields: []string{ "Field4" } }).Patch(&testInstance, map[string]interface{}{
+      "Field4": map[string]interface{}{ "Field1": "test", "Field4": map[string]interface{}{ "Field1": "test" } },
+    })
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+    if !reflect.DeepEqual(testInstance, TestStruct{}) || !reflect.DeepEqual(result.Unpermitted, []string{"Field4.Field1", "Field4.Field4"}) {
+      t.Errorf("Expected \"Field4\" to permit no fields inside it. Patch affected struct so: %v, unpermitted [%v]", testInstance, strings.Join(result.Unpermitted, ", "))
+      return
+    }
+
+    // Test to see if invalid patterns are returned as errors rather than panicking.
+    for _, pattern := range([]string{ "Field4..Field1", "!", "Field[" }) {
+      cfg := PatcherConfig{ PermittedFields: []string{ pattern } }
+      if _, err := NewPatcher(cfg); err == nil {
+        t.Errorf("Expected NewPatcher to return an error for invalid pattern %q", pattern)
+      }
+      if _, err := New(cfg).Patch(&TestStruct{}, map[string]interface{}{ "Field1": "test" }); err == nil {
+        t.Errorf("Expected Patch to return an error for invalid pattern %q", pattern)
+      }
+    }
+  })
+
+  t.Run("deny-by-default", func(t *testing.T) {
+
+    type TestPublic struct {
+      Name     string  `gopatch:"public"`
+      IsAdmin  bool
+    }
+
+    cfg := PatcherConfig{
+      DenyByDefault: true,
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestPublic{}
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Name": "test",
+      "IsAdmin": true,
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if only the public field was patched.
+    if testInstance.Name != "test" || testInstance.IsAdmin {
+      t.Errorf("Expected patch to only patch Name. Patch affected struct so: %v", testInstance)
+      return
+    }
+    if len(result.Unpermitted) != 1 || result.Unpermitted[0] != "IsAdmin" {
+      t.Errorf("Expected patch result unpermitted to contain exactly \"IsAdmin\". Contained [%v]", strings.Join(result.Unpermitted, ", "))
+      return
+    }
+
+    // Test to see if exclusions alone grant nothing.
+    patcher = New(PatcherConfig{ DenyByDefault: true, PermittedFields: []string{ "!Name" } })
+    result, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "IsAdmin": true,
+    })
+    if err != nil || testInstance.IsAdmin || len(result.Unpermitted) != 1 {
+      t.Errorf("Expected patch to leave IsAdmin unpermitted. Patch affected struct so: %v, with error: %v", testInstance, err)
+      return
+    }
+    type TestPublicNested struct {
+      Profile   TestPublic
+      Settings  TestDouble  `gopatch:"public"`
+    }
+
+    // Test to see if public fields inside structs are reachable, while public structs don't permit the fields inside them.
+    nested := TestPublicNested{}
+    result, err = New(cfg).Patch(&nested, map[string]interface{}{
+      "Profile": map[string]interface{}{ "Name": "test", "IsAdmin": true },
+      "Settings": map[string]interface{}{ "Field1": "test" },
+    })
+    if err != nil || nested.Profile.Name != "test" || nested.Profile.IsAdmin || nested.Settings.Field1 != "" {
+      t.Errorf("Expected patch to only patch Profile.Name. Patch affected struct so: %v, with error: %v", nested, err)
+      return
+    }
+    if !reflect.DeepEqual(result.Unpermitted, []string{"Profile.IsAdmin", "Settings.Field1"}) {
+      t.Errorf("Expected patch result unpermitted to contain exactly \"Profile.IsAdmin\" and \"Settings.Field1\". Contained [%v]", strings.Join(result.Unpermitted, ", "))
+      return
+    }
+  })
+
+  t.Run("roles", func(t *testing.T) {
+
+    type TestRoles struct {
+      Name      string
+      Verified  bool    `gopatch:"roles=admin|support"`
+      IsAdmin   bool    `gopatch:"roles=admin"`
+    }
+
+    cfg := PatcherConfig{
+      PermittedFields: []string{ "Name", "Verified" },
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestRoles{}
+
+    result, err := patcher.PatchAs(&testInstance, map[string]interface{}{
+      "Name": "test",
+      "Verified": true,
+      "IsAdmin": true,
+    }, "support")
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the role-restricted fields were only patched for the caller's roles.
+    if testInstance.Name != "test" || !testInstance.Verified || testInstance.IsAdmin {
+      t.Errorf("Expected patch to only patch Name and Verified. Patch affected struct so: %v", testInstance)
+      return
+    }
+    if len(result.Unpermitted) != 1 || result.UnpermittedReasons["IsAdmin"] != UnpermittedByRole {
+      t.Errorf("Expected patch result unpermitted to contain exactly \"IsAdmin\" due to its role. Contained %v", result.UnpermittedReasons)
+      return
+    }
+
+    // Test to see if role-restricted fields are still limited by the permitted array, and denied to callers without roles.
+    testInstance = TestRoles{}
+    result, err = patcher.PatchAs(&testInstance, map[string]interface{}{ "IsAdmin": true }, "admin")
+    if err != nil || testInstance.IsAdmin || result.UnpermittedReasons["IsAdmin"] != UnpermittedByArray {
+      t.Errorf("Expected IsAdmin to be unpermitted by the permitted array. Patch affected struct so: %v, with error: %v", testInstance, err)
+      return
+    }
+    result, err = patcher.Patch(&testInstance, map[string]interface{}{ "Verified": true })
+    if err != nil || testInstance.Verified || result.UnpermittedReasons["Verified"] != UnpermittedByRole {
+      t.Errorf("Expected Verified to be unpermitted without roles. Patch affected struct so: %v, with error: %v", testInstance, err)
+      return
+    }
+  })
+
+  t.Run("authorizer", func(t *testing.T) {
+
+    type tenantKey struct{}
+
+    cfg := PatcherConfig{
+      Authorizer: AuthorizerFunc(func(ctx context.Context, path string, field reflect.StructField, current, new interface{}) (bool, error) {
+        if path == "Field4.Field2" { return ctx.Value(tenantKey{}) == "admin", nil }
+        return true, nil
+      }),
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestStruct{}
+
+    ctx := context.WithValue(context.Background(), tenantKey{}, "guest")
+    result, err := patcher.PatchContext(ctx, &testInstance, map[string]interface{}{
+      "Field1": "test",
+      "Field4": map[string]interface{}{ "Field1": "test", "Field2": 255 },
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the authorizer's decision was applied to the nested field.
+    if testInstance.Field1 != "test" || testInstance.Field4.Field1 != "test" || testInstance.Field4.Field2 != 0 {
+      t.Errorf("Expected patch to patch all but Field4.Field2. Patch affected struct so: %v", testInstance)
+      return
+    }
+    if result.UnpermittedReasons["Field4.Field2"] != UnpermittedByAuthorizer {
+      t.Errorf("Expected \"Field4.Field2\" to be unpermitted by the authorizer. Unpermitted %v", result.UnpermittedReasons)
+      return
+    }
+
+    // Test to see if a cancelled context stops the patch and undoes it.
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    testInstance = TestStruct{}
+    _, err = patcher.PatchContext(ctx, &testInstance, map[string]interface{}{ "Field1": "test" })
+    if !errors.Is(err, context.Canceled) || testInstance.Field1 != "" {
+      t.Errorf("Expected cancelled patch to fail without patching. Patch affected struct so: %v, with error: %v", testInstance, err)
+      return
+    }
+  })
+
+  t.Run("transitions", func(t *testing.T) {
+
+    type TestStatus string
+
+    type TestDocument struct {
+      Status  TestStatus  `gopatch:"transitions=draft>review,review>published,review>draft"`
+    }
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestDocument{ Status: "draft" }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Status": "published",
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the forbidden transition was unpermitted.
+    if testInstance.Status != "draft" || result.UnpermittedReasons["Status"] != UnpermittedByTransition {
+      t.Errorf("Expected transition from draft to published to be unpermitted. Patch affected struct so: %v", testInstance)
+      return
+    }
+
+    // Test to see if allowed transitions are patched.
+    for _, status := range([]TestStatus{ "review", "draft", "review", "published" }) {
+      if _, err := patcher.Patch(&testInstance, map[string]interface{}{ "Status": string(status) }); err != nil || testInstance.Status != status {
+        t.Errorf("Expected transition to %q to be patched. Patch affected struct so: %v, with error: %v", status, testInstance, err)
+        return
+      }
+    }
+
+    // Test to see if forbidden transitions error when unpermitted fields do.
+    patcher = New(PatcherConfig{ UnpermittedErrors: true })
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{ "Status": "draft" })
+    if !errors.Is(err, ErrUnpermitted) || testInstance.Status != "published" {
+      t.Errorf("Expected unpermitted patch error, but got: %v", err)
+      return
+    }
+
+    type TestArticle struct {
+      State  testState  `gopatch:"transitions=draft>review,review>published"`
+    }
+
+    // Test to see if transitions of enums are checked by their names, after converting the patched values.
+    article := TestArticle{ State: testDraft }
+    result, err = New(PatcherConfig{}).Patch(&article, map[string]interface{}{ "State": 2.0 })
+    if err != nil || article.State != testDraft || result.UnpermittedReasons["State"] != UnpermittedByTransition {
+      t.Errorf("Expected transition from draft to published to be unpermitted. Patch affected struct so: %v, with error: %v", article, err)
+      return
+    }
+    for _, state := range([]testState{ testReview, testPublished }) {
+      if _, err := New(PatcherConfig{}).Patch(&article, map[string]interface{}{ "State": float64(state) }); err != nil || article.State != state {
+        t.Errorf("Expected transition to %q to be patched. Patch affected struct so: %v, with error: %v", state, article, err)
+        return
+      }
+    }
+  })
+
+  t.Run("once-immutable", func(t *testing.T) {
+
+    type TestAccount struct {
+      ExternalID  string  `gopatch:"once"`
+      Region      string  `gopatch:"immutable"`
+    }
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestAccount{ Region: "eu" }
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "ExternalID": "abc",
+      "Region": "eu",
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the zero-value write-once field was set, and the repeated immutable value accepted.
+    if testInstance.ExternalID != "abc" || len(result.Unpermitted) != 0 {
+      t.Errorf("Expected patch to set ExternalID and accept Region. Patch affected struct so: %v, unpermitting [%v]", testInstance, strings.Join(result.Unpermitted, ", "))
+      return
+    }
+
+    // Test to see if both fields now reject changes.
+    result, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "ExternalID": "def",
+      "Region": "us",
+    })
+    if err != nil || testInstance.ExternalID != "abc" || testInstance.Region != "eu" {
+      t.Errorf("Expected patch to change neither field. Patch affected struct so: %v, with error: %v", testInstance, err)
+      return
+    }
+    expected := map[string]UnpermittedReason{ "ExternalID": UnpermittedByOnce, "Region": UnpermittedByImmutable }
+    if !reflect.DeepEqual(result.UnpermittedReasons, expected) {
+      t.Errorf("Expected patch result unpermitted reasons to be %v. Were %v", expected, result.UnpermittedReasons)
+      return
+    }
+
+    type TestImmutables struct {
+      Field1  time.Time    `gopatch:"immutable"`
+      Field2  *time.Time   `gopatch:"immutable"`
+      Field3  TestDouble   `gopatch:"immutable"`
+      Field4  null.String  `gopatch:"immutable"`
+      Field5  null.String  `gopatch:"immutable"`
+    }
+
+    created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+    immutables := TestImmutables{
+      Field1: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+      Field2: &created,
+      Field3: TestDouble{ Field1: "test", Field2: 255 },
+      Field4: null.StringFrom("test"),
+    }
+
+    // Test to see if immutable fields accept their current values once converted to the fields' types.
+    result, err = patcher.Patch(&immutables, map[string]interface{}{
+      "Field1": "2024-01-02T04:04:05+01:00",
+      "Field2": "2024-01-02T03:04:05Z",
+      "Field3": map[string]interface{}{ "Field1": "test", "Field2": 255.0 },
+      "Field4": "test",
+      "Field5": nil,
+    })
+    if err != nil || len(result.Unpermitted) != 0 {
+      t.Errorf("Expected patch to accept the current values of immutable fields. Unpermitted [%v], with error: %v", strings.Join(result.Unpermitted, ", "), err)
+      return
+    }
+
+    // Test to see if immutable fields still reject changed values.
+    result, err = patcher.Patch(&immutables, map[string]interface{}{
+      "Field1": "2024-01-02T03:04:05+01:00",
+      "Field2": nil,
+      "Field3": map[string]interface{}{ "Field1": "test", "Field2": 1.0 },
+      "Field4": nil,
+      "Field5": "changed",
+    })
+    if err != nil || !reflect.DeepEqual(result.Unpermitted, []string{"Field1", "Field2", "Field3", "Field4", "Field5"}) {
+      t.Errorf("Expected patch to reject changes to every immutable field. Unpermitted [%v], with error: %v", strings.Join(result.Unpermitted, ", "), err)
+      return
+    }
+    type TestImmutablePeriod struct {
+      Period  testPeriod  `gopatch:"immutable"`
+    }
+
+    // Test to see if checking an immutable struct doesn't call the authorizer for the value it's compared with.
+    authorized := []string{}
+    period := TestImmutablePeriod{ Period: testPeriod{ Start: 1, End: 5 } }
+    result, err = New(PatcherConfig{
+      Authorizer: AuthorizerFunc(func(ctx context.Context, path string, field reflect.StructField, current, new interface{}) (bool, error) {
+        authorized = append(authorized, path)
+        return true, nil
+      }),
+    }).Patch(&period, map[string]interface{}{
+      "Period": map[string]interface{}{ "Start": 1.0, "End": 5.0 },
+    })
+    if err != nil || len(result.Unpermitted) != 0 || !reflect.DeepEqual(authorized, []string{"Period", "Period.Start", "Period.End"}) {
+      t.Errorf("Expected the authorizer to be called once for each field patched. Called for [%v], unpermitting [%v], with error: %v", strings.Join(authorized, ", "), strings.Join(result.Unpermitted, ", "), err)
+      return
+    }
+  })
+
+  t.Run("validation", func(t *testing.T) {
+
+    type TestProfile struct {
+      Username  string    `patchvalidate:"min=3,max=8,regex=^[a-z]{1,8}$"`
+      Email     string    `patchvalidate:"email"`
+      Age       int       `patchvalidate:"min=18"`
+      Color     string    `patchvalidate:"oneof=red green"`
+      Tags      []string  `patchvalidate:"len=2"`
+    }
+
+    cfg := PatcherConfig{
+      CollectErrors: true,
+    }
+
+    patcher := New(cfg)
+
+    testInstance := TestProfile{}
+
+    _, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Username": "No",
+      "Email": "not an email",
+      "Age": 12,
+      "Color": "blue",
+      "Tags": []interface{}{ "a" },
+    })
+
+    // Test to see if every invalid field was reported.
+    errs, ok := err.(ErrorList)
+    if !ok || len(errs) != 5 || !errors.Is(err, ErrValidation) {
+      t.Errorf("Expected validation error list of 5 errors, but got: %v", err)
+      return
+    }
+    var validationErr *ValidationError
+    if !errors.As(errs[0], &validationErr) || errs[0].Path != "Username" || validationErr.Rule != "min" {
+      t.Errorf("Expected Username to fail its \"min\" rule, but got: %v", errs[0])
+      return
+    }
+    if !reflect.DeepEqual(testInstance, TestProfile{}) {
+      t.Errorf("Expected failed patch to be undone. Patch affected struct so: %v", testInstance)
+      return
+    }
+
+    // Test to see if valid values are patched.
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "Username": "nifty",
+      "Email": "nifty@example.com",
+      "Age": 18,
+      "Color": "green",
+      "Tags": []interface{}{ "a", "b" },
+    })
+    if err != nil || testInstance.Username != "nifty" || len(testInstance.Tags) != 2 {
+      t.Errorf("Expected valid patch to be applied. Patch affected struct so: %v, with error: %v", testInstance, err)
+      return
+    }
+  })
+
+  t.Run("patch-validator", func(t *testing.T) {
+
+    type TestBooking struct {
+      Name    string
+      Period  testPeriod  `gopatch:"patch"`
+    }
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestBooking{ Period: testPeriod{ Start: 1, End: 5 } }
+
+    _, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Name": "test",
+      "Period": map[string]interface{}{ "End": 1 },
+    })
+
+    // Test to see if the invariant failed, undoing the whole patch.
+    var patchErr *PatchError
+    if !errors.As(err, &patchErr) || patchErr.Path != "Period" || !errors.Is(err, ErrValidation) {
+      t.Errorf("Expected validation patch error at \"Period\", but got: %v", err)
+      return
+    }
+    if testInstance.Name != "" || testInstance.Period.End != 5 {
+      t.Errorf("Expected failed patch to be undone. Patch affected struct so: %v", testInstance)
+      return
+    }
+
+    // Test to see if patches keeping the invariant are applied.
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "Period": map[string]interface{}{ "Start": 2, "End": 9 },
+    })
+    if err != nil || testInstance.Period != (testPeriod{ Start: 2, End: 9 }) {
+      t.Errorf("Expected valid patch to be applied. Patch affected struct so: %v, with error: %v", testInstance, err)
+      return
+    }
+  })
+
+  t.Run("hooks", func(t *testing.T) {
+
+    type TestBlog struct {
+      Article  testArticle  `gopatch:"patch"`
+    }
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := TestBlog{}
+    patch := map[string]interface{}{
+      "Article.Title": "  Hello World ",
+    }
+
+    result, err := patcher.Patch(&testInstance, patch)
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if the hooks rewrote the patch and derived the slug, adding it to the results.
+    if testInstance.Article.Title != "Hello World" || testInstance.Article.Slug != "hello-world" {
+      t.Errorf("Expected hooks to trim the title and derive the slug. Patch affected struct so: %v", testInstance)
+      return
+    }
+    if v, e := result.Map["Article.Slug"]; !e || v != "hello-world" {
+      t.Errorf("Expected patch result map to contain \"Article.Slug\": \"hello-world\". Contained %v", result.Map)
+      return
+    }
+    if patch["Article.Title"] != "  Hello World " {
+      t.Errorf("Expected the caller's patch to be left untouched. Was %v", patch)
+      return
+    }
+
+    // Test to see if a rejecting hook fails the patch.
+    testInstance.Article.Locked = true
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "Article": map[string]interface{}{ "Title": "Changed" },
+    })
+    if err == nil || testInstance.Article.Title != "Hello World" {
+      t.Errorf("Expected locked article to reject the patch. Patch affected struct so: %v, with error: %v", testInstance, err)
+      return
+    }
+
+    // Test to see if a root hook's results are relative to it, and prepended with the embed path along with the rest.
+    article := testArticle{}
+    result, err = New(PatcherConfig{ EmbedPath: "doc" }).Patch(&article, map[string]interface{}{ "Title": "Hello" })
+    if err != nil || !reflect.DeepEqual(result.Fields, []string{"doc.Title", "doc.Slug"}) {
+      t.Errorf("Expected patch result fields to be exactly \"doc.Title\" and \"doc.Slug\". Were [%v], with error: %v", strings.Join(result.Fields, ", "), err)
+      return
+    }
+    if v, e := result.Map["doc.Slug"]; !e || v != "hello" || len(result.Map) != 2 || result.Changes[1].Path != "doc.Slug" {
+      t.Errorf("Expected patch result map and changes to use \"doc.Slug\". Map contained %v, changes %v", result.Map, result.Changes)
+      return
+    }
+  })
+
+  t.Run("setters", func(t *testing.T) {
+
+    cfg := PatcherConfig{}
+
+    patcher := New(cfg)
+
+    testInstance := testMember{}
+
+    result, err := patcher.Patch(&testInstance, map[string]interface{}{
+      "Name": "nifty",
+      "email": "nifty@example.com",
+    })
+
+    // Test for unexpected errors.
+    if err != nil {
+      t.Errorf("Unexpected patch error: %q", err.Error())
+      return
+    }
+
+    // Test to see if both fields were set by their setters, including the unexported one.
+    if testInstance.Name != "Nifty" || testInstance.email != "nifty@example.com" {
+      t.Errorf("Expected setters to set Name and email. Patch affected struct so: %v", testInstance)
+      return
+    }
+    if len(result.Changes) != 2 || result.Changes[0].New != "Nifty" {
+      t.Errorf("Expected patch result changes to hold the values set. Held %v", result.Changes)
+      return
+    }
+
+    // Test to see if a setter's error fails the patch and undoes it.
+    _, err = patcher.Patch(&testInstance, map[string]interface{}{
+      "Name": "changed",
+      "email": "invalid",
+    })
+    var patchErr *PatchError
+    if !errors.Is(err, ErrSetter) || !errors.As(err, &patchErr) || patchErr.Path != "email" {
+      t.Errorf("Expected setter patch error at \"email\", but got: %v", err)
+      return
+    }
+    if testInstance.Name != "Nifty" || testInstance.email != "nifty@example.com" {
+      t.Errorf("Expected failed patch to be undone. Patch affected struct so: %v", testInstance)
+      return
+    }
+
+    // Test to see if a struct field permitted by an exact pattern is passed to its setter whole.
+    author := testAuthor{}
+    result, err = New(PatcherConfig{ PermittedFields: []string{ "Prof" } }).Patch(&author, map[string]interface{}{
+      "Prof": map[string]interface{}{ "Bio": "test", "Site": "example.com" },
+    })
+    if err != nil || author.Prof != (testProfile{ Bio: "test", Site: "example.com" }) || len(result.Unpermitted) != 0 {
+      t.Errorf("Expected setter to receive the whole profile. Patch affected struct so: %v, unpermitting [%v], with error: %v", author, strings.Join(result.Unpermitted, ", "), err)
+      return
+    }
+
+    // Test to see if a struct field with a setter isn't set when only paths inside it are permitted.
+    result, err = New(PatcherConfig{ PermittedFields: []string{ "Prof.Bio" } }).Patch(&author, map[string]interface{}{
+      "Prof": map[string]interface{}{ "Bio": "changed", "Site": "changed" },
+    })
+    if err != nil || author.Prof.Bio != "test" || !reflect.DeepEqual(result.Unpermitted, []string{"Prof"}) {
+      t.Errorf("Expected Prof to be unpermitted. Patch affected struct so: %v, unpermitting [%v], with error: %v", author, strings.Join(result.Unpermitted, ", "), err)
+      return
+    }
+  })
 }
\ No newline at end of file
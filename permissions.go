@@ -0,0 +1,178 @@
+package gopatch
+
+import(
+  "fmt"
+  "path"
+  "strings"
+)
+
+// permissionPattern is a compiled PermittedFields pattern, split into its segments in dot notation.
+type permissionPattern struct {
+  segments []string
+  exclude  bool
+}
+
+// permissions tracks which PermittedFields patterns match a field as a patch recurses through the destination. Each pattern is matched segment by
+// segment against the field's path, so a field's permissions are found by calling "child" on its parent's. A pattern only permits the fields it
+// matches exactly, while one ending in "**" matches everything inside them too. Only "deep" grants and exclusions are passed down to children. The
+// zero value permits everything.
+type permissions struct {
+  patterns []permissionPattern
+  states   [][]int
+  granted  bool
+  deep     bool
+  excluded bool
+}
+
+// compilePermissions compiles "fields" into the permissions of a patch's root. Unless denying by default, an empty array permits everything, as
+// does an array holding only exclusions, apart from what they exclude.
+func compilePermissions(fields []string, denyByDefault bool) (permissions, error) {
+
+  if len(fields) == 0 && !denyByDefault { return permissions{}, nil }
+
+  patterns := make([]permissionPattern, 0, len(fields)+1)
+  grants := false
+  for _, field := range(fields) {
+
+    pattern := permissionPattern{}
+    if strings.HasPrefix(field, "!") {
+      pattern.exclude = true
+      field = field[1:]
+    }
+    if field == "" { return permissions{}, fmt.Errorf("gopatch: empty permitted field pattern") }
+
+    pattern.segments = strings.Split(field, ".")
+    for _, segment := range(pattern.segments) {
+      if segment == "" { return permissions{}, fmt.Errorf("gopatch: empty segment in permitted field pattern `%s`", field) }
+      if _, err := path.Match(segment, ""); err != nil { return permissions{}, fmt.Errorf("gopatch: invalid permitted field pattern `%s`: %w", field, err) }
+    }
+
+    // A trailing "*" permits the field and everything inside it, as it always has.
+    if last := len(pattern.segments)-1; pattern.segments[last] == "*" { pattern.segments[last] = "**" }
+
+    grants = grants || !pattern.exclude
+    patterns = append(patterns, pattern)
+  }
+  if !grants && !denyByDefault { patterns = append(patterns, permissionPattern{ segments: []string{ "**" } }) }
+
+  root := permissions{ patterns: patterns, states: make([][]int, len(patterns)) }
+  for i, pattern := range(patterns) {
+    root.states[i] = pattern.reach(nil, 0)
+    root.mark(pattern, root.states[i])
+  }
+
+  return root, nil
+}
+
+// child returns the permissions of the field or element "name" inside the current one.
+func (p permissions) child(name string) permissions {
+
+  if p.patterns == nil { return p }
+
+  out := permissions{ patterns: p.patterns, states: make([][]int, len(p.patterns)), granted: p.deep, deep: p.deep, excluded: p.excluded }
+  for i, pattern := range(p.patterns) {
+
+    var next []int
+    for _, pos := range(p.states[i]) {
+
+      if pos == len(pattern.segments) { continue }
+
+      // A "**" segment matches any number of segments, so it may consume this one and stay in place.
+      segment := pattern.segments[pos]
+      if segment == "**" {
+        next = pattern.reach(next, pos)
+      } else if matchSegment(segment, name) {
+        next = pattern.reach(next, pos+1)
+      }
+    }
+
+    out.states[i] = next
+    out.mark(pattern, next)
+  }
+
+  return out
+}
+
+// grant returns the current permissions with the current field permitted, unless excluded. Fields inside it are still checked in turn.
+func (p permissions) grant() permissions {
+
+  p.granted = true
+  return p
+}
+
+// grantAll returns the current permissions with the current field permitted, along with everything inside it not excluded. This is used for
+// values which replace a permitted field as a whole, such as a slice replacing the field's elements, so their contents aren't checked field by
+// field against patterns which only name the field itself.
+func (p permissions) grantAll() permissions {
+
+  p.granted = true
+  p.deep = true
+  return p
+}
+
+// allows returns whether the field or element "name" inside the current one is permitted.
+func (p permissions) allows(name string) bool {
+
+  return p.child(name).permitted()
+}
+
+// permitted returns whether the current field is permitted. Fields are permitted if a pattern matches them, or a pattern ending in "**" matches
+// any field containing them, unless an exclusion matches them or any field containing them.
+func (p permissions) permitted() bool {
+
+  return p.patterns == nil || (p.granted && !p.excluded)
+}
+
+// within returns whether any field inside the current one could be permitted.
+func (p permissions) within() bool {
+
+  if p.patterns == nil || p.deep { return !p.excluded }
+  if p.excluded { return false }
+
+  for i, pattern := range(p.patterns) {
+    if pattern.exclude { continue }
+    for _, pos := range(p.states[i]) {
+      if pos < len(pattern.segments) { return true }
+    }
+  }
+
+  return false
+}
+
+// mark records the pattern as matching the current field if "states" reached its end. Patterns ending in "**" match everything inside it too.
+func (p *permissions) mark(pattern permissionPattern, states []int) {
+
+  for _, pos := range(states) {
+    if pos != len(pattern.segments) { continue }
+    if pattern.exclude {
+      p.excluded = true
+    } else {
+      p.granted = true
+      p.deep = p.deep || pattern.segments[len(pattern.segments)-1] == "**"
+    }
+  }
+}
+
+// reach adds "pos" to "states", along with the positions after any "**" segments starting at it, which may match no segments at all.
+func (p permissionPattern) reach(states []int, pos int) []int {
+
+  for {
+    for _, s := range(states) {
+      if s == pos { return states }
+    }
+    states = append(states, pos)
+
+    if pos == len(p.segments) || p.segments[pos] != "**" { return states }
+    pos++
+  }
+}
+
+// matchSegment returns whether "name" matches the pattern segment "segment", which may be a glob such as "addr*".
+func matchSegment(segment, name string) bool {
+
+  if segment == "*" || segment == name { return true }
+  if !strings.ContainsAny(segment, `*?[\`) { return false }
+
+  matched, _ := path.Match(segment, name)
+  return matched
+}
\ No newline at end of file
@@ -0,0 +1,71 @@
+package gopatch
+
+import(
+  "fmt"
+  "reflect"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// fieldSetter is a setter method found for a field, along with the struct it belongs to.
+type fieldSetter struct {
+  owner  reflect.Value
+  method reflect.Value
+}
+
+// findSetter finds the setter method of "field" in "owner", an addressable struct. The method is named by the "gopatch" tag option "setter", such
+// as `gopatch:"setter=ChangeEmail"`, or by convention as "Set" followed by the field's name, such as SetEmail for either "Email" or "email". A
+// setter takes a single value of the field's type and returns an error. Methods found by convention with any other signature are ignored, while
+// those named by the tag result in an error, as do named methods which don't exist. If there is no setter, nil is returned.
+func findSetter(owner reflect.Value, field reflect.StructField) (*fieldSetter, error) {
+
+  name, named := parseTag(field).option("setter")
+  if !named {
+    first, size := utf8.DecodeRuneInString(field.Name)
+    name = "Set"+string(unicode.ToUpper(first))+field.Name[size:]
+  }
+
+  method := owner.Addr().MethodByName(strings.TrimSpace(name))
+  if !method.IsValid() {
+    if named { return nil, fmt.Errorf("method `%s` not found", name) }
+    return nil, nil
+  }
+
+  t := method.Type()
+  if t.NumIn() != 1 || !field.Type.AssignableTo(t.In(0)) || t.NumOut() != 1 || t.Out(0) != errorType {
+    if named { return nil, fmt.Errorf("method `%s` must have the signature `func(%v) error`", name, field.Type) }
+    return nil, nil
+  }
+
+  return &fieldSetter{ owner: owner, method: method }, nil
+}
+
+// set patches "fieldV" with "val" by calling its setter, after converting the value to the field's type using the same rules used for slice
+// elements, including Updaters. The field is set as a whole, so structs, slices and maps aren't patched by path, and are built with everything
+// inside them permitted, as the field itself is. The owning struct is recorded
+// first, so any changes the setter makes to it can be undone. Setters aren't called during a dry run, which sets the field directly instead.
+func (p Patcher) set(results *PatchResult, fieldT reflect.StructField, fieldV reflect.Value, setter *fieldSetter, val interface{}, permitted permissions, state *patchState) error {
+
+  newV, ok, err := p.convert(fieldT.Type, reflect.ValueOf(val), permitted.grantAll(), state)
+  if err != nil { return err }
+  if !ok { return p.skipField(state.path, fieldT.Name, SkipIncompatibleType, state) }
+
+  old := fieldV.Interface()
+  if p.skipUnchanged(state) && equalValues(fieldV, newV) { return nil }
+  if err := p.validate(fieldT, newV, state.path); err != nil { return err }
+
+  if state.dryRun {
+    state.journal.set(fieldV, newV)
+    return p.saveToResults(results, fieldT, val, old, fieldV.Interface())
+  }
+
+  state.journal.record(setter.owner)
+  if out := setter.method.Call([]reflect.Value{ newV }); !out[0].IsNil() {
+    return errFieldSetter(state.path, fieldT.Name, out[0].Interface().(error))
+  }
+
+  return p.saveToResults(results, fieldT, val, old, fieldV.Interface())
+}
\ No newline at end of file
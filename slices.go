@@ -0,0 +1,269 @@
+package gopatch
+
+import(
+  "fmt"
+  "reflect"
+  "sort"
+  "strconv"
+)
+
+// convertSlice converts "v", which must be a slice or array, into a new slice or array of type "t" by converting each of its elements. A nil "v"
+// results in the zero value of "t". Arrays may be patched with fewer elements than their length, leaving the rest at their zero values.
+func (p Patcher) convertSlice(t reflect.Type, v reflect.Value, permitted permissions, state *patchState) (reflect.Value, bool, error) {
+
+  if !v.IsValid() { return reflect.Zero(t), true, nil }
+  if v.Kind() != reflect.Slice && v.Kind() != reflect.Array { return reflect.Value{}, false, nil }
+
+  // Allocate the new slice or array.
+  var out reflect.Value
+  if t.Kind() == reflect.Array {
+    if v.Len() > t.Len() { return reflect.Value{}, false, nil }
+    out = reflect.New(t).Elem()
+  } else {
+    out = reflect.MakeSlice(t, v.Len(), v.Len())
+  }
+
+  // Convert each element, unwrapping interface{} elements such as those found in []interface{}.
+  for i := 0; i < v.Len(); i++ {
+
+    elem := v.Index(i)
+    if elem.Kind() == reflect.Interface { elem = elem.Elem() }
+
+    // Elements are permitted by index.
+    prev := state.enter(strconv.Itoa(i))
+    converted, ok, err := p.convert(t.Elem(), elem, permitted.child(strconv.Itoa(i)), state)
+    state.path = prev
+    if err != nil || !ok { return reflect.Value{}, ok, err }
+
+    out.Index(i).Set(converted)
+  }
+
+  return out, true, nil
+}
+
+// MergeDeleteKey is the key which, when set to true in an element patched into a slice with the "merge" gopatch tag, removes the matching element
+// from the slice.
+const MergeDeleteKey = "$delete"
+
+// mergeSlice merges "v", a slice of maps, into "fieldV", a slice of structs or pointers to structs, matching elements by their "key" field. Matched
+// elements are deep-patched, unmatched elements are appended, and elements marked with MergeDeleteKey are removed. Results are recorded with keyed
+// paths such as `items[id=42].qty`. If "v" isn't a slice, or the elements aren't structs with the key field, the field is skipped. Elements of "v"
+// without a key, or with one which can't be converted to the key field's type, are skipped by their index in "v".
+func (p Patcher) mergeSlice(results *PatchResult, dest reflect.StructField, fieldV, v reflect.Value, key string, permitted permissions, state *patchState) error {
+
+  if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) { return p.skipField(state.path, dest.Name, SkipIncompatibleType, state) }
+
+  // Find the key field of the slice's struct elements.
+  elemT := fieldV.Type().Elem()
+  structT := elemT
+  if structT.Kind() == reflect.Ptr { structT = structT.Elem() }
+  if structT.Kind() != reflect.Struct { return p.skipField(state.path, dest.Name, SkipUnsupportedKind, state) }
+
+  keyIndex := -1
+  for i := 0; i < structT.NumField(); i++ {
+    if name, err := p.patchName(structT.Field(i)); err == nil && name == key {
+      keyIndex = i
+      break
+    }
+  }
+  if keyIndex < 0 { return p.skipField(state.path, dest.Name, SkipUnsupportedKind, state) }
+
+  fieldName, mapName, err := p.resultNames(dest)
+  if err != nil { return err }
+
+  // Work on a copy of the slice, so the field can be restored by assigning the original slice.
+  merged := reflect.MakeSlice(fieldV.Type(), fieldV.Len(), fieldV.Len())
+  reflect.Copy(merged, fieldV)
+
+  for i := 0; i < v.Len(); i++ {
+
+    elem := v.Index(i)
+    if elem.Kind() == reflect.Interface { elem = elem.Elem() }
+    m, ok := map[string]interface{}(nil), false
+    if elem.IsValid() { m, ok = elem.Interface().(map[string]interface{}) }
+    if !ok {
+      if err := p.skipField(state.pathTo(strconv.Itoa(i)), dest.Name, SkipIncompatibleType, state); err != nil { return err }
+      continue
+    }
+
+    // Separate the key and deletion marker from the rest of the element's patch.
+    keyVal, hasKey := m[key]
+    remove, _ := m[MergeDeleteKey].(bool)
+    rest := make(map[string]interface{}, len(m))
+    for k, val := range(m) {
+      if k != key && k != MergeDeleteKey { rest[k] = val }
+    }
+
+    // Skip elements without a usable key, which can't be matched or reported under a keyed path.
+    if !hasKey || keyVal == nil {
+      if err := p.skipField(state.pathTo(strconv.Itoa(i)), dest.Name, SkipMissingKey, state); err != nil { return err }
+      continue
+    }
+    converted, ok, err := p.convert(structT.Field(keyIndex).Type, reflect.ValueOf(keyVal), permissions{}, state)
+    if err != nil { return err }
+    if !ok {
+      if err := p.skipField(state.pathTo(strconv.Itoa(i)), dest.Name, SkipIncompatibleType, state); err != nil { return err }
+      continue
+    }
+
+    // Find the existing element with a matching key.
+    found := -1
+    for j := 0; j < merged.Len(); j++ {
+      existing := merged.Index(j)
+      if existing.Kind() == reflect.Ptr {
+        if existing.IsNil() { continue }
+        existing = existing.Elem()
+      }
+      if equalValues(existing.Field(keyIndex), converted) {
+        found = j
+        break
+      }
+    }
+
+    keyed := "["+key+"="+fmt.Sprint(keyVal)+"]"
+
+    // Remove the matched element if marked for deletion.
+    if remove {
+      if found < 0 { continue }
+      if !permitted.allows(strconv.Itoa(found)) {
+        if err := p.unpermit(state.path+keyed, dest.Name, UnpermittedByArray, state); err != nil { return err }
+        continue
+      }
+
+      old := merged.Index(found).Interface()
+      merged = reflect.AppendSlice(merged.Slice(0, found), merged.Slice(found+1, merged.Len()))
+      results.Fields = append(results.Fields, fieldName+keyed)
+      results.Map[mapName+keyed] = nil
+      results.Changes = append(results.Changes, Change{ Path: mapName+keyed, Old: old, New: nil })
+      continue
+    }
+
+    // Deep-patch the matched element in place.
+    if found >= 0 {
+      existing := merged.Index(found)
+      if existing.Kind() == reflect.Ptr { existing = existing.Elem() }
+
+      prev := state.path
+      state.path += keyed
+      deep, err := p.patch(existing.Addr().Interface(), rest, permitted.child(strconv.Itoa(found)), state)
+      state.path = prev
+      if err != nil { return err }
+
+      appendResults(results, deep, fieldName+keyed, mapName+keyed)
+      continue
+    }
+
+    // Append unmatched elements, built from their zero value with the key included.
+    if !permitted.allows(strconv.Itoa(merged.Len())) {
+      if err := p.unpermit(state.path+keyed, dest.Name, UnpermittedByArray, state); err != nil { return err }
+      continue
+    }
+
+    prev := state.path
+    state.path += keyed
+    added, ok, err := p.convert(elemT, reflect.ValueOf(m), permitted.child(strconv.Itoa(merged.Len())), state)
+    state.path = prev
+    if err != nil { return err }
+    if !ok {
+      if err := p.skipField(state.path+keyed, dest.Name, SkipIncompatibleType, state); err != nil { return err }
+      continue
+    }
+
+    merged = reflect.Append(merged, added)
+    results.Fields = append(results.Fields, fieldName+keyed)
+    results.Map[mapName+keyed] = added.Interface()
+    results.Changes = append(results.Changes, Change{ Path: mapName+keyed, Old: nil, New: added.Interface() })
+  }
+
+  if err := p.validate(dest, merged, state.path); err != nil { return err }
+
+  state.journal.set(fieldV, merged)
+
+  return nil
+}
+
+// patchIndexes patches the elements of "fieldV", a slice or array, addressed by the keys of "m", which must be indexes. Elements which are structs
+// are deep-patched, while other elements are replaced. Results are recorded with the index in the path, such as `addresses.1.city`. Indexes which
+// are negative, out of range, or not numbers at all result in an error.
+func (p Patcher) patchIndexes(results *PatchResult, dest reflect.StructField, fieldV reflect.Value, m map[string]interface{}, permitted permissions, state *patchState) error {
+
+  fieldName, mapName, err := p.resultNames(dest)
+  if err != nil { return err }
+
+  // Work on a copy of the slice or array, so the field can be restored by assigning the original.
+  patched := reflect.New(fieldV.Type()).Elem()
+  if fieldV.Kind() == reflect.Slice {
+    patched = reflect.MakeSlice(fieldV.Type(), fieldV.Len(), fieldV.Len())
+    reflect.Copy(patched, fieldV)
+  } else {
+    patched.Set(fieldV)
+  }
+
+  // Patch indexes in order, so results are predictable.
+  m = expandPaths(m, expandsElem(fieldV.Type()))
+  indexes := make([]string, 0, len(m))
+  for k := range(m) { indexes = append(indexes, k) }
+  sort.Strings(indexes)
+
+  changed := false
+  for _, index := range(indexes) {
+
+    i, err := strconv.Atoi(index)
+    if err != nil || i < 0 || i >= patched.Len() {
+      if err := state.fail(errFieldIndex(state.pathTo(index), dest.Name, index)); err != nil { return err }
+      continue
+    }
+
+    elem := patched.Index(i)
+    val := m[index]
+
+    // Deep-patch struct elements, allocating nil pointers to structs first.
+    if isPatchedByPath(elem, val, fieldTag{}) && elem.Kind() != reflect.Slice && elem.Kind() != reflect.Array {
+      if elem.Kind() == reflect.Ptr {
+        if elem.IsNil() { elem.Set(reflect.New(elem.Type().Elem())) }
+        elem = elem.Elem()
+      }
+
+      prev := state.enter(index)
+      deep, err := p.patch(elem.Addr().Interface(), val.(map[string]interface{}), permitted.child(index), state)
+      state.path = prev
+      if err != nil { return err }
+
+      appendResults(results, deep, fieldName+"."+index, mapName+"."+index)
+      changed = true
+      continue
+    }
+
+    // Skip element or error if it isn't permitted by the array.
+    if !permitted.allows(index) {
+      if err := p.unpermit(state.pathTo(index), dest.Name, UnpermittedByArray, state); err != nil { return err }
+      continue
+    }
+
+    // Replace any other element with the converted value.
+    prev := state.enter(index)
+    converted, ok, err := p.convert(elem.Type(), reflect.ValueOf(val), permitted.child(index), state)
+    state.path = prev
+    if err != nil { return err }
+    if !ok {
+      if err := p.skipField(state.pathTo(index), dest.Name, SkipIncompatibleType, state); err != nil { return err }
+      continue
+    }
+
+    old := elem.Interface()
+    if p.skipUnchanged(state) && equalValues(elem, converted) { continue }
+
+    elem.Set(converted)
+    results.Fields = append(results.Fields, fieldName+"."+index)
+    results.Map[mapName+"."+index] = val
+    results.Changes = append(results.Changes, Change{ Path: mapName+"."+index, Old: old, New: elem.Interface() })
+    changed = true
+  }
+
+  if !changed { return nil }
+  if err := p.validate(dest, patched, state.path); err != nil { return err }
+
+  state.journal.set(fieldV, patched)
+
+  return nil
+}
\ No newline at end of file
@@ -0,0 +1,119 @@
+package gopatch
+
+import(
+  "reflect"
+  "strings"
+)
+
+// tagFlags is the set of valueless options recognized in the "gopatch" tag. Any other option without a value continues the value of the option
+// before it, allowing values such as lists to contain commas.
+var tagFlags = map[string]bool{
+  "-": true,
+  "patch": true,
+  "replace": true,
+  "merge": true,
+  "public": true,
+  "once": true,
+  "immutable": true,
+}
+
+// fieldTag is the parsed form of a field's "gopatch" tag, which holds comma-separated flags such as "merge" and options such as "key=id".
+type fieldTag struct {
+  flags   map[string]bool
+  options map[string]string
+}
+
+// parseTag parses the "gopatch" tag of "field".
+func parseTag(field reflect.StructField) fieldTag {
+
+  return parseOptions(field.Tag.Get("gopatch"), tagFlags)
+}
+
+// parseOptions parses "value", a tag's comma-separated list of options, recognizing "flags" as valueless options.
+func parseOptions(value string, flags map[string]bool) fieldTag {
+
+  tag := fieldTag{
+    flags: map[string]bool{},
+    options: map[string]string{},
+  }
+
+  last := ""
+  for _, part := range(strings.Split(value, ",")) {
+
+    part = strings.TrimSpace(part)
+    if part == "" { continue }
+
+    // Options take the form "name=value".
+    if i := strings.Index(part, "="); i >= 0 {
+      last = part[:i]
+      tag.options[last] = part[i+1:]
+      continue
+    }
+
+    // Unrecognized flags following an option are part of its value.
+    if !flags[part] && last != "" {
+      tag.options[last] += ","+part
+      continue
+    }
+
+    tag.flags[part] = true
+    last = ""
+  }
+
+  return tag
+}
+
+// containsPublic returns whether "t", or the structs, slices and maps it holds, contain any field tagged "public", in which case the fields of a
+// value of type "t" must be reachable for them to be patched.
+func containsPublic(t reflect.Type) bool {
+
+  return findPublic(t, map[reflect.Type]bool{})
+}
+
+// findPublic implements containsPublic, skipping any type in "seen" so recursive types end.
+func findPublic(t reflect.Type, seen map[reflect.Type]bool) bool {
+
+  for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map { t = t.Elem() }
+  if t.Kind() != reflect.Struct || seen[t] { return false }
+  seen[t] = true
+
+  for i := 0; i < t.NumField(); i++ {
+
+    tag := parseTag(t.Field(i))
+    if tag.has("-") { continue }
+    if tag.has("public") || findPublic(t.Field(i).Type, seen) { return true }
+  }
+
+  return false
+}
+
+// has returns whether the tag contains "flag".
+func (t fieldTag) has(flag string) bool {
+
+  return t.flags[flag]
+}
+
+// option returns the value of the option "name", and whether it exists.
+func (t fieldTag) option(name string) (string, bool) {
+
+  value, ok := t.options[name]
+  return value, ok
+}
+
+// allowsTransition returns whether "transitions", the value of the "transitions" tag option, allows a field to change from "from" to "to". It's a
+// comma-separated list of rules such as "draft>review", either side of which may be "*" to match any value. Unchanged values are always allowed.
+func allowsTransition(transitions, from, to string) bool {
+
+  if from == to { return true }
+
+  for _, rule := range(strings.Split(transitions, ",")) {
+
+    i := strings.Index(rule, ">")
+    if i < 0 { continue }
+
+    ruleFrom, ruleTo := strings.TrimSpace(rule[:i]), strings.TrimSpace(rule[i+1:])
+    if (ruleFrom == "*" || ruleFrom == from) && (ruleTo == "*" || ruleTo == to) { return true }
+  }
+
+  return false
+}
\ No newline at end of file
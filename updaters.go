@@ -8,17 +8,20 @@ import (
   "github.com/guregu/null"
 )
 
-// MapUpdater updates any map as long as the key and element values match.
+// MapUpdater updates any map as long as the key kinds match and the value can be converted to the map's type.
 func MapUpdater(fieldValue reflect.Value, v reflect.Value) bool {
   if fieldValue.Kind() != reflect.Map { return false }
-  if fieldValue.Type().Key().Kind() != v.Type().Key().Kind() { return false }
-  if fieldValue.Type().Elem().Kind() != v.Type().Elem().Kind() {
-    if !v.Type().ConvertibleTo(fieldValue.Type()) { return false }
 
-    fieldValue.Set(v.Convert(fieldValue.Type()))
+  // if its null value
+  if !v.IsValid() {
+    fieldValue.Set(reflect.Zero(fieldValue.Type()))
+    return true
   }
-  
-  fieldValue.Set(v)
+  if v.Kind() != reflect.Map { return false }
+  if fieldValue.Type().Key().Kind() != v.Type().Key().Kind() { return false }
+  if !v.Type().ConvertibleTo(fieldValue.Type()) { return false }
+
+  fieldValue.Set(v.Convert(fieldValue.Type()))
   return true
 }
 
@@ -152,7 +155,7 @@ func NullTimeUpdater(fieldValue reflect.Value, v reflect.Value) bool {
 func BoolUpdater(fieldValue reflect.Value, v reflect.Value) bool {
   if fieldValue.Kind() == reflect.Bool {
     if v.Kind() == reflect.Bool {
-      fieldValue.Set(v)
+      fieldValue.SetBool(v.Bool())
       return true
     }
   } else if fieldValue.Kind() == reflect.Ptr {
@@ -0,0 +1,153 @@
+package gopatch
+
+import(
+  "fmt"
+  "net/mail"
+  "reflect"
+  "regexp"
+  "strconv"
+  "strings"
+  "sync"
+  "unicode/utf8"
+)
+
+// PatchValidator is implemented by structs which check invariants across their fields, such as a start date preceding an end date, which rules on
+// individual fields can't express. If "dest", or any struct patched inside it, is a PatchValidator, ValidatePatch is called once its fields are
+// patched, with results holding the Fields, Map and Changes of the patch made to it, relative to it. If ValidatePatch returns an error, the patch
+// fails with a PatchError caused by it, with the code CodeValidation, and every change made to "dest" is undone.
+type PatchValidator interface {
+  ValidatePatch(results *PatchResult) error
+}
+
+// validateFlags is the set of valueless rules recognized in the "patchvalidate" tag.
+var validateFlags = map[string]bool{
+  "email": true,
+}
+
+// validateRules is the order in which the rules of the "patchvalidate" tag are checked.
+var validateRules = []string{ "len", "min", "max", "oneof", "regex", "email" }
+
+// validateRegexps caches the compiled regular expressions of "regex" rules, by expression.
+var validateRegexps sync.Map
+
+// ValidationError is the cause of a PatchError returned when a patched value fails a rule of its field's "patchvalidate" tag. It holds the rule,
+// its parameter, if any, and a message describing what the value must be.
+type ValidationError struct {
+  Rule    string
+  Param   string
+  Message string
+}
+
+func (e *ValidationError) Error() string {
+
+  rule := e.Rule
+  if e.Param != "" { rule += "="+e.Param }
+
+  return ErrValidation.Error()+" on `"+rule+"`: "+e.Message
+}
+
+// validate checks "v", the value about to be assigned to "field", against the rules of the field's "patchvalidate" tag, such as
+// `patchvalidate:"min=3,max=20"`. The value is checked after any conversion, so rules apply to the field's type rather than the patch's.
+func (p *Patcher) validate(field reflect.StructField, v reflect.Value, path string) error {
+
+  value, ok := field.Tag.Lookup("patchvalidate")
+  if !ok { return nil }
+
+  // Nil pointers and interfaces hold no value to validate.
+  for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+    if v.IsNil() { return nil }
+    v = v.Elem()
+  }
+
+  rules := parseOptions(value, validateFlags)
+  for _, rule := range(validateRules) {
+
+    param, ok := rules.option(rule)
+    if !ok && !rules.has(rule) { continue }
+
+    if message := checkRule(rule, param, v); message != "" {
+      return &PatchError{ Path: path, Field: field.Name, Code: CodeValidation, Cause: &ValidationError{ Rule: rule, Param: param, Message: message } }
+    }
+  }
+
+  return nil
+}
+
+// checkRule checks "v" against "rule" with the parameter "param", returning a message describing what the value must be if it fails.
+func checkRule(rule, param string, v reflect.Value) string {
+
+  switch rule {
+
+  // Numbers are compared by value, while strings, slices, arrays and maps are compared by length.
+  case "len", "min", "max":
+    limit, err := strconv.ParseFloat(param, 64)
+    if err != nil { return "invalid parameter" }
+
+    n, isNumber := number(v)
+    if !isNumber {
+      length, hasLength := length(v)
+      if !hasLength { return "must be a number, string, slice or map" }
+      n = float64(length)
+    }
+
+    subject := "must be"
+    if !isNumber { subject = "must have a length of" }
+    if rule == "len" && n != limit { return subject+" exactly "+param }
+    if rule == "min" && n < limit { return subject+" at least "+param }
+    if rule == "max" && n > limit { return subject+" at most "+param }
+
+  case "oneof":
+    s := fmt.Sprint(v.Interface())
+    for _, option := range(strings.Fields(param)) {
+      if s == option { return "" }
+    }
+    return "must be one of ["+param+"]"
+
+  case "regex":
+    if v.Kind() != reflect.String { return "must be a string" }
+
+    re, ok := validateRegexps.Load(param)
+    if !ok {
+      compiled, err := regexp.Compile(param)
+      if err != nil { return "invalid parameter" }
+      re, _ = validateRegexps.LoadOrStore(param, compiled)
+    }
+    if !re.(*regexp.Regexp).MatchString(v.String()) { return "must match `"+param+"`" }
+
+  case "email":
+    if v.Kind() != reflect.String { return "must be a string" }
+
+    address, err := mail.ParseAddress(v.String())
+    if err != nil || address.Address != v.String() { return "must be an email address" }
+  }
+
+  return ""
+}
+
+// number returns the value of "v" as a float64, and whether it's a number.
+func number(v reflect.Value) (float64, bool) {
+
+  switch v.Kind() {
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    return float64(v.Int()), true
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+    return float64(v.Uint()), true
+  case reflect.Float32, reflect.Float64:
+    return v.Float(), true
+  }
+
+  return 0, false
+}
+
+// length returns the length of "v" in characters for strings, or elements for slices, arrays and maps, and whether it has a length.
+func length(v reflect.Value) (int, bool) {
+
+  switch v.Kind() {
+  case reflect.String:
+    return utf8.RuneCountInString(v.String()), true
+  case reflect.Slice, reflect.Array, reflect.Map:
+    return v.Len(), true
+  }
+
+  return 0, false
+}
\ No newline at end of file